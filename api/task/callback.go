@@ -30,6 +30,6 @@ func Callback(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	container.CallbackTask(task.ID, callback.Status, callback.Message, callback.Result)
+	container.CallbackTask(task.ID, callback.Status, callback.Result, callback.Message)
 	ctx.JSON(http.StatusOK, gin.H{"message": "回调成功"})
 }
@@ -1,19 +1,91 @@
 package task
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"run-task/container"
+	"sort"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// TaskConfigGroup 按分类分组的配置
+type TaskConfigGroup struct {
+	Category string                  `json:"category"`
+	Configs  []*container.TaskConfig `json:"configs"`
+}
+
+// TaskConfigTagGroup 按标签分组的配置
+type TaskConfigTagGroup struct {
+	Tag     string                  `json:"tag"`
+	Configs []*container.TaskConfig `json:"configs"`
+}
+
+// GetTaskConfigList 获取配置列表，支持 category、tag 过滤，group_by=category 或 tag 时分组返回
+//
+// 按标签分组时有多个标签的配置出现在每个标签下，没有标签的配置归入 tag 为空的组。
 func GetTaskConfigList(ctx *gin.Context) {
-	configList, err := container.GetTaskConfigList()
+	configList, err := container.GetTaskConfigList(container.TaskConfigFilter{
+		Category: ctx.Query("category"),
+		Tag:      ctx.Query("tag"),
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, configList)
+	switch ctx.Query("group_by") {
+	case "category":
+		names, grouped := groupTaskConfigs(configList, func(config *container.TaskConfig) []string {
+			return []string{config.Category}
+		})
+		groups := make([]*TaskConfigGroup, 0, len(names))
+		for _, name := range names {
+			groups = append(groups, &TaskConfigGroup{Category: name, Configs: grouped[name]})
+		}
+		ctx.JSON(http.StatusOK, groups)
+	case "tag":
+		names, grouped := groupTaskConfigs(configList, func(config *container.TaskConfig) []string {
+			if len(config.Tags) == 0 {
+				return []string{""}
+			}
+			return config.Tags
+		})
+		groups := make([]*TaskConfigTagGroup, 0, len(names))
+		for _, name := range names {
+			groups = append(groups, &TaskConfigTagGroup{Tag: name, Configs: grouped[name]})
+		}
+		ctx.JSON(http.StatusOK, groups)
+	default:
+		ctx.JSON(http.StatusOK, configList)
+	}
+}
+
+// groupTaskConfigs 按 keys 返回的组名分组，组名排序后返回，空组名排在最后，组内保持列表顺序
+func groupTaskConfigs(configList []*container.TaskConfig, keys func(*container.TaskConfig) []string) ([]string, map[string][]*container.TaskConfig) {
+	var names []string
+	grouped := map[string][]*container.TaskConfig{}
+	for _, config := range configList {
+		seen := map[string]bool{}
+		for _, name := range keys(config) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, ok := grouped[name]; !ok {
+				names = append(names, name)
+			}
+			grouped[name] = append(grouped[name], config)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		if names[i] == "" || names[j] == "" {
+			return names[j] == "" && names[i] != ""
+		}
+		return names[i] < names[j]
+	})
+	return names, grouped
 }
 
 func CreateTaskConfig(ctx *gin.Context) {
@@ -22,8 +94,12 @@ func CreateTaskConfig(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := container.CreateTaskConfig(&config); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := container.ValidateTaskConfig(&config); err != nil {
+		respondConfigError(ctx, err)
+		return
+	}
+	if err := container.CreateTaskConfig(&config, getOperator(ctx)); err != nil {
+		respondConfigError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, config)
@@ -35,22 +111,174 @@ func DeleteTaskConfig(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := container.DeleteTaskConfig(config.TaskType); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if rejectManagedConfig(ctx, config.TaskType) {
+		return
+	}
+	if err := container.DeleteTaskConfig(config.TaskType, getOperator(ctx)); err != nil {
+		respondConfigError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "Task config deleted successfully"})
 }
 
 func UpdateTaskConfig(ctx *gin.Context) {
-	var config container.TaskConfig
-	if err := ctx.ShouldBindJSON(&config); err != nil {
+	body, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var taskType string
+	if err := json.Unmarshal(fields["task_type"], &taskType); err != nil || taskType == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_type不能为空"})
+		return
+	}
+	if rejectManagedConfig(ctx, taskType) {
+		return
+	}
+	existing := container.GetTaskConfig(taskType)
+	if existing == nil {
+		respondConfigError(ctx, container.ErrTaskConfigNotFound)
+		return
+	}
+	config, err := patchTaskConfig(existing, fields, body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := container.ValidateTaskConfig(config); err != nil {
+		respondConfigError(ctx, err)
+		return
+	}
+	if err := container.UpdateTaskConfig(taskType, config, getOperator(ctx)); err != nil {
+		respondConfigError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, config)
+}
+
+// patchTaskConfig 只用请求中出现的字段覆盖已有配置，未出现的字段保持不变
+func patchTaskConfig(existing *container.TaskConfig, fields map[string]json.RawMessage, body []byte) (*container.TaskConfig, error) {
+	config := *existing
+	// 解码到已有的 map 会合并键，出现时整体替换
+	if _, ok := fields["dispatch_headers"]; ok {
+		config.DispatchHeaders = nil
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+	config.TaskType = existing.TaskType
+	return &config, nil
+}
+
+// GetTaskConfigRevisions 获取任务类型的修订历史
+func GetTaskConfigRevisions(ctx *gin.Context) {
+	taskType := ctx.Query("task_type")
+	if taskType == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_type不能为空"})
+		return
+	}
+	revisions, err := container.ListTaskConfigRevisions(taskType)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// CompareTaskConfigRevisions 比较同一任务类型的两个修订
+func CompareTaskConfigRevisions(ctx *gin.Context) {
+	taskType := ctx.Query("task_type")
+	from, errFrom := strconv.Atoi(ctx.Query("from"))
+	to, errTo := strconv.Atoi(ctx.Query("to"))
+	if taskType == "" || errFrom != nil || errTo != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_type、from、to参数是必需的"})
+		return
+	}
+	_, fromConfig, err := container.GetTaskConfigRevision(taskType, from)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "修订不存在", "revision": from})
+		return
+	}
+	_, toConfig, err := container.GetTaskConfigRevision(taskType, to)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "修订不存在", "revision": to})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"task_type": taskType,
+		"from":      from,
+		"to":        to,
+		"changes":   container.DiffTaskConfig(fromConfig, toConfig),
+	})
+}
+
+// RollbackTaskConfigRequest 回滚配置的请求
+type RollbackTaskConfigRequest struct {
+	TaskType string `json:"task_type"`
+	Revision int    `json:"revision"`
+}
+
+// RollbackTaskConfig 将配置回滚到指定修订
+func RollbackTaskConfig(ctx *gin.Context) {
+	var req RollbackTaskConfigRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := container.UpdateTaskConfig(config.TaskType, &config); err != nil {
+	if rejectManagedConfig(ctx, req.TaskType) {
+		return
+	}
+	if _, _, err := container.GetTaskConfigRevision(req.TaskType, req.Revision); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "修订不存在"})
+		return
+	}
+	config, err := container.RollbackTaskConfig(req.TaskType, req.Revision, getOperator(ctx))
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, config)
 }
+
+// respondConfigError 按错误类型返回 400、404、409 或 500
+func respondConfigError(ctx *gin.Context, err error) {
+	var validationErr *container.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validationErr.Fields})
+	case errors.Is(err, container.ErrTaskConfigNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, container.ErrTaskConfigExists):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// rejectManagedConfig 配置由配置目录管理时返回 403，调用方应直接返回
+func rejectManagedConfig(ctx *gin.Context, taskType string) bool {
+	config := container.GetTaskConfig(taskType)
+	if config == nil || !config.Managed {
+		return false
+	}
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":  "配置由配置目录管理，只读",
+		"source": config.ManagedSource,
+	})
+	return true
+}
+
+// GetTaskConfigDrift 比较配置目录中的文件与数据库中的配置
+func GetTaskConfigDrift(ctx *gin.Context) {
+	drifts, err := container.GetConfigDrift()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, drifts)
+}
@@ -0,0 +1,108 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"run-task/container"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+)
+
+// ExportTaskConfig 以 YAML 或 JSON 配置包下载全部或指定的配置
+func ExportTaskConfig(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format只支持yaml或json"})
+		return
+	}
+	var taskTypes []string
+	for _, value := range ctx.QueryArray("task_type") {
+		for _, taskType := range strings.Split(value, ",") {
+			if taskType = strings.TrimSpace(taskType); taskType != "" {
+				taskTypes = append(taskTypes, taskType)
+			}
+		}
+	}
+
+	bundle, err := container.ExportTaskConfigs(taskTypes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		data        []byte
+		contentType string
+	)
+	if format == "json" {
+		data, err = json.MarshalIndent(bundle, "", "  ")
+		contentType = "application/json"
+	} else {
+		data, err = yaml.Marshal(bundle)
+		contentType = "application/yaml"
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("task-config-%s.%s", time.Now().Format("20060102150405"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// ImportTaskConfig 导入 YAML 或 JSON 配置包，支持 merge、overwrite 模式和 dry_run
+func ImportTaskConfig(ctx *gin.Context) {
+	data, err := readImportBody(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// YAML 是 JSON 的超集，两种格式都按 YAML 解析
+	bundle, err := container.ParseTaskConfigBundle(data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "配置包解析失败：" + err.Error()})
+		return
+	}
+
+	for _, config := range bundle.Configs {
+		if rejectManagedConfig(ctx, config.TaskType) {
+			return
+		}
+	}
+
+	mode := ctx.DefaultQuery("mode", container.ImportModeMerge)
+	dryRun := ctx.Query("dry_run") == "true" || ctx.Query("dry_run") == "1"
+	results, valid, err := container.ImportTaskConfigs(bundle, mode, dryRun, getOperator(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "results": results})
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "配置包校验失败，未写入任何配置", "results": results})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"dry_run": dryRun, "mode": mode, "results": results})
+}
+
+// readImportBody 读取配置包内容，支持直接提交和以 file 字段上传文件
+func readImportBody(ctx *gin.Context) ([]byte, error) {
+	if !strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		return ctx.GetRawData()
+	}
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,192 @@
+package task
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"run-task/container"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// dryRunHeader 配置测试发出的请求带有该请求头与 dry_run=1 参数，执行端应只做连通性检查
+	dryRunHeader = "X-Task-Dry-Run"
+	// 配置测试最多读取的响应体字节数
+	maxTestResponseBody = 64 * 1024
+)
+
+// TestTaskConfigRequest 测试配置的请求
+//
+// config 不为空时测试尚未保存的配置，否则测试 task_type 对应的已保存配置；
+// send 为 true 时才会真正向执行端发送请求。未保存的配置可以指向任意地址，带有认证、客户端证书
+// 或引用密钥的请求头时只能预览，不能发送。
+type TestTaskConfigRequest struct {
+	TaskType string                `json:"task_type"`
+	Config   *container.TaskConfig `json:"config"`
+	Input    string                `json:"input"`
+	Send     bool                  `json:"send"`
+}
+
+// DispatchPreview 将要发送给执行端的请求，密钥相关的请求头已脱敏
+type DispatchPreview struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Input   json.RawMessage   `json:"input"`
+}
+
+// DispatchTimings 请求各阶段耗时，单位毫秒，未发生的阶段为 0
+type DispatchTimings struct {
+	DNS       float64 `json:"dns"`
+	Connect   float64 `json:"connect"`
+	TLS       float64 `json:"tls"`
+	FirstByte float64 `json:"first_byte"` // 从发出请求到收到首字节
+	Total     float64 `json:"total"`
+}
+
+// DispatchTestResult 实际发送请求的结果
+type DispatchTestResult struct {
+	StatusCode int             `json:"status_code"`
+	Body       string          `json:"body"`
+	Truncated  bool            `json:"truncated"`
+	Error      string          `json:"error,omitempty"`
+	Timings    DispatchTimings `json:"timings"`
+}
+
+// TestTaskConfig 渲染 runTask 将要发送的请求，可选地以 dry-run 方式发送并返回各阶段耗时
+func TestTaskConfig(ctx *gin.Context) {
+	var request TestTaskConfigRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	taskConfig := request.Config
+	if taskConfig == nil {
+		if taskConfig = container.GetTaskConfig(request.TaskType); taskConfig == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": container.ErrTaskConfigNotFound.Error()})
+			return
+		}
+	} else if err := container.ValidateTaskConfig(taskConfig); err != nil {
+		respondConfigError(ctx, err)
+		return
+	} else if request.Send && taskConfig.HasDispatchCredentials() {
+		respondConfigError(ctx, &container.ValidationError{Fields: []container.FieldError{{Field: "send", Message: "未保存的配置带有下发认证或密钥引用，保存后才能发送"}}})
+		return
+	}
+
+	// 样例输入与创建任务时一样渲染模板并校验，但不保存
+	input := request.Input
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	if container.HasInputTemplate(input) {
+		rendered, err := container.RenderInputTemplate(input, container.TemplateContext{
+			TaskType: taskConfig.TaskType,
+			User:     getOperator(ctx),
+		})
+		if err != nil {
+			respondCreateError(ctx, err)
+			return
+		}
+		input = rendered
+	}
+	if err := container.ValidateTaskInput(taskConfig.Form, input); err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+
+	task := &container.Task{TaskType: taskConfig.TaskType, Input: input, Token: "dry-run"}
+	client, dispatch, err := newDispatchRequest(task, taskConfig, taskConfig.RunEndpoint)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := dispatch.URL.Query()
+	query.Set("dry_run", "1")
+	dispatch.URL.RawQuery = query.Encode()
+	dispatch.Header.Set(dryRunHeader, "true")
+
+	response := gin.H{"request": previewDispatch(dispatch, taskConfig, input)}
+	if request.Send {
+		response["response"] = sendTestDispatch(client, dispatch)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// previewDispatch 返回请求预览，认证信息与引用了密钥的请求头不返回明文
+func previewDispatch(request *http.Request, taskConfig *container.TaskConfig, input string) *DispatchPreview {
+	secretHeaders := map[string]bool{}
+	for name, value := range taskConfig.DispatchHeaders {
+		if strings.Contains(value, "${secret:") {
+			secretHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	headers := map[string]string{}
+	for name := range request.Header {
+		value := request.Header.Get(name)
+		if name == "Authorization" || secretHeaders[name] {
+			value = container.RedactedValue
+		}
+		headers[name] = value
+	}
+	return &DispatchPreview{
+		Method:  request.Method,
+		URL:     request.URL.String(),
+		Headers: headers,
+		Input:   json.RawMessage(input),
+	}
+}
+
+// sendTestDispatch 发送请求并通过 httptrace 记录各阶段耗时
+//
+// 使用不复用连接的新连接池，避免命中下发连接池中的空闲连接而测不到 DNS、连接与 TLS 耗时。
+func sendTestDispatch(client *http.Client, request *http.Request) *DispatchTestResult {
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		fresh := transport.Clone()
+		fresh.DisableKeepAlives = true
+		defer fresh.CloseIdleConnections()
+		client = &http.Client{Transport: fresh, Timeout: client.Timeout}
+	}
+	result := &DispatchTestResult{}
+	var dnsStart, connectStart, tlsStart time.Time
+	since := func(start time.Time) float64 {
+		return float64(time.Since(start).Microseconds()) / 1000
+	}
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { result.Timings.DNS = since(dnsStart) },
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { result.Timings.Connect = since(connectStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { result.Timings.TLS = since(tlsStart) },
+		GotFirstResponseByte: func() {
+			result.Timings.FirstByte = since(start)
+		},
+	}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+
+	resp, err := client.Do(request)
+	if err != nil {
+		result.Error = err.Error()
+		result.Timings.Total = since(start)
+		return result
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTestResponseBody+1))
+	result.Timings.Total = since(start)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if len(body) > maxTestResponseBody {
+		body, result.Truncated = body[:maxTestResponseBody], true
+	}
+	result.StatusCode = resp.StatusCode
+	result.Body = string(body)
+	return result
+}
@@ -0,0 +1,51 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"slices"
+	"testing"
+)
+
+func TestTestTaskConfig(t *testing.T) {
+	saved := saveTestConfig(t, "/check", func(c *container.TaskConfig) {
+		c.AuthType, c.AuthPassword = "bearer", "runner-token"
+	})
+
+	tests := []struct {
+		name   string
+		body   TestTaskConfigRequest
+		status int
+		field  string
+	}{
+		{"saved config", TestTaskConfigRequest{TaskType: saved.TaskType}, http.StatusOK, ""},
+		{"saved config send", TestTaskConfigRequest{TaskType: saved.TaskType, Send: true}, http.StatusOK, ""},
+		{"unknown config", TestTaskConfigRequest{TaskType: "missing"}, http.StatusNotFound, ""},
+		{"inline config", TestTaskConfigRequest{Config: &container.TaskConfig{TaskType: "inline", RunEndpoint: runnerURL + "/inline"}, Send: true}, http.StatusOK, ""},
+		{"inline invalid config", TestTaskConfigRequest{Config: &container.TaskConfig{TaskType: "inline", RunEndpoint: "ftp://runner"}}, http.StatusBadRequest, "run_endpoint"},
+		{"inline credentials send", TestTaskConfigRequest{Config: &container.TaskConfig{TaskType: "inline", RunEndpoint: runnerURL + "/inline", AuthType: "bearer", AuthPassword: "${secret:runner-token}"}, Send: true}, http.StatusBadRequest, "send"},
+		{"invalid input", TestTaskConfigRequest{TaskType: saved.TaskType, Input: "[1]"}, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := doRequest(t, http.MethodPost, "/api/task/config/test", tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if tt.field != "" && !slices.Contains(fieldErrors(response), tt.field) {
+				t.Fatalf("fields = %v, want %s", fieldErrors(response), tt.field)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			preview, _ := response["request"].(map[string]any)
+			headers, _ := preview["headers"].(map[string]any)
+			if auth, ok := headers["Authorization"]; ok && auth != container.RedactedValue {
+				t.Fatalf("preview leaked Authorization %v", auth)
+			}
+			if result, ok := response["response"].(map[string]any); tt.body.Send && (!ok || result["status_code"] != float64(http.StatusOK)) {
+				t.Fatalf("send response = %v, want status 200", response["response"])
+			}
+		})
+	}
+}
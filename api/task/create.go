@@ -1,6 +1,7 @@
 package task
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,35 +11,172 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// CreateTaskRequest 创建任务的请求，指定 preset_id 时 input 中的字段覆盖预设输入
+//
+// 只接受调用方可以设置的字段，状态、结果、修订号、时间等由服务端维护。
+type CreateTaskRequest struct {
+	TaskType    string `json:"task_type"`
+	Input       string `json:"input"`
+	RunEndpoint string `json:"run_endpoint"`
+	PresetID    uint   `json:"preset_id"`
+}
+
 // CreateTask 创建任务
 func CreateTask(ctx *gin.Context) {
-	var task container.Task
-	if err := ctx.ShouldBindJSON(&task); err != nil {
+	var req CreateTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	taskID, err := container.CreateTask(task.TaskType, task.Input, task.RunEndpoint)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	task := container.Task{TaskType: req.TaskType, Input: req.Input, RunEndpoint: req.RunEndpoint, Creator: getOperator(ctx)}
+	if err := applyPreset(ctx, &task, req.PresetID); err != nil {
+		respondCreateError(ctx, err)
 		return
 	}
-	err = runTask(taskID, task.RunEndpoint)
-	if err != nil {
-		container.CallbackTask(taskID, "error", "", err.Error())
-		ctx.JSON(http.StatusOK, gin.H{"error": err.Error(), "task_id": taskID})
+	if err := createTask(&task); err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	respondCreated(ctx, &task)
+}
+
+// respondCreated 下发新建的任务并输出响应，任务排队时返回 202，响应中带上任务使用的配置修订
+func respondCreated(ctx *gin.Context, task *container.Task) {
+	if task.Status == container.TaskStatusQueued {
+		ctx.JSON(http.StatusAccepted, gin.H{"task_id": task.ID, "config_revision": task.ConfigRevision, "status": task.Status, "message": queuedMessage(task.TaskType)})
+		return
+	}
+	if err := DispatchTask(task.ID); err != nil {
+		ctx.JSON(http.StatusOK, gin.H{"error": err.Error(), "task_id": task.ID, "config_revision": task.ConfigRevision})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"task_id": taskID})
+	ctx.JSON(http.StatusOK, gin.H{"task_id": task.ID, "config_revision": task.ConfigRevision})
+}
+
+// createTask 检查任务类型是否启用并按表单校验输入后保存任务，RunEndpoint 为空时使用任务类型配置中的地址
+//
+// 批量创建、定时创建等入口都应通过该函数创建任务。
+func createTask(task *container.Task) error {
+	taskConfig := container.GetTaskConfig(task.TaskType)
+	status, err := container.CheckTaskTypeEnabled(taskConfig)
+	if err != nil {
+		return err
+	}
+	// 下发认证只发给配置中的执行地址，配置了认证的任务类型不能改用其他地址
+	if taskConfig != nil && task.RunEndpoint != "" && task.RunEndpoint != taskConfig.RunEndpoint && taskConfig.HasDispatchCredentials() {
+		return &container.ValidationError{Fields: []container.FieldError{{Field: "run_endpoint", Message: "任务类型配置了下发认证，不能指定其他执行地址"}}}
+	}
+	// 输入中的模板在创建时渲染，原始模板单独保存
+	task.InputTemplate = ""
+	if container.HasInputTemplate(task.Input) {
+		rendered, err := container.RenderInputTemplate(task.Input, container.TemplateContext{
+			TaskType: task.TaskType,
+			User:     task.Creator,
+		})
+		if err != nil {
+			return err
+		}
+		task.InputTemplate, task.Input = task.Input, rendered
+	}
+
+	var form string
+	if taskConfig != nil {
+		form = taskConfig.Form
+	}
+	if err := container.ValidateTaskInput(form, task.Input); err != nil {
+		return err
+	}
+	task.ID = 0
+	task.Status = status
+	return container.CreateTask(task)
+}
+
+// queuedMessage 任务排队时返回给调用方的提示
+func queuedMessage(taskType string) string {
+	if taskConfig := container.GetTaskConfig(taskType); taskConfig != nil && taskConfig.MaintenanceMessage != "" {
+		return taskConfig.MaintenanceMessage
+	}
+	return "任务类型维护中，任务已排队，恢复后自动执行"
+}
+
+// respondCreateError 输出创建任务失败的响应，输入校验失败时返回字段级错误，任务类型停用时返回 503
+func respondCreateError(ctx *gin.Context, err error) {
+	var (
+		validationErr *container.ValidationError
+		disabledErr   *container.TaskTypeDisabledError
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validationErr.Fields})
+	case errors.As(err, &disabledErr):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "task_type": disabledErr.TaskType})
+	case errors.Is(err, container.ErrTaskPresetNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// DispatchTask 按任务记录的配置修订将任务发送给执行端，失败时将任务标记为 error
+func DispatchTask(taskID uint) error {
+	task := container.GetTask(taskID)
+	if task == nil {
+		return fmt.Errorf("任务不存在")
+	}
+	if err := container.MarkTaskDispatched(taskID); err != nil {
+		return err
+	}
+	if err := runTask(task, task.RunEndpoint); err != nil {
+		container.CallbackTask(taskID, container.TaskStatusError, "", err.Error())
+		return err
+	}
+	return nil
 }
 
 // RunTaskSSE 处理SSE请求
-func runTask(taskID uint, endpoint string) error {
+func runTask(task *container.Task, endpoint string) error {
+	taskID := task.ID
+	client, request, err := newDispatchRequest(task, container.TaskDispatchConfig(task), endpoint)
+	if err != nil {
+		return err
+	}
+	result, err := client.Do(request)
+	if err != nil {
+		// 错误信息中去掉带任务令牌的查询参数，相同原因的失败也能归为一组
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = endpoint
+		}
+		return err
+	}
+	defer result.Body.Close()
+	if result.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(result.Body)
+		container.CallbackTask(taskID, container.TaskStatusError, "", string(body))
+		return fmt.Errorf("任务运行失败，状态码：%d，响应体：%s", result.StatusCode, string(body))
+	}
+	return nil
+}
 
+// newDispatchRequest 构造下发给执行端的请求，runTask 与配置测试共用
+//
+// 只有配置中的执行地址才会收到任务令牌、请求头、认证与客户端证书，调用方指定的其他地址只能拿到脱敏后的输入。
+func newDispatchRequest(task *container.Task, taskConfig *container.TaskConfig, endpoint string) (*http.Client, *http.Request, error) {
 	cfg := container.GetConfig()
+	taskID := task.ID
+	trusted := taskConfig != nil && endpoint == taskConfig.RunEndpoint
+	if !trusted {
+		taskConfig = taskConfig.WithoutDispatchCredentials()
+	}
 
+	input := fmt.Sprintf("%s/api/task/input/%d", cfg.AppHost, taskID)
+	if trusted {
+		// 输入地址带上任务令牌，执行端无需改动即可拿到替换了密钥引用的输入
+		input += "?token=" + task.Token
+	}
 	query := map[string]string{
-		"input":    fmt.Sprintf("%s/api/task/input/%d", cfg.AppHost, taskID),
+		"input":    input,
 		"output":   fmt.Sprintf("%s/api/task/output/%d", cfg.AppHost, taskID),
 		"callback": fmt.Sprintf("%s/api/task/callback/%d", cfg.AppHost, taskID),
 	}
@@ -49,15 +187,19 @@ func runTask(taskID uint, endpoint string) error {
 	}
 	runEndpoint := fmt.Sprintf("%s?%s", endpoint, urlValues.Encode())
 
-	result, err := http.Get(runEndpoint)
+	client, err := container.DispatchClient(taskConfig)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer result.Body.Close()
-	if result.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(result.Body)
-		container.CallbackTask(taskID, "error", "", string(body))
-		return fmt.Errorf("任务运行失败，状态码：%d，响应体：%s", result.StatusCode, string(body))
+	request, err := http.NewRequest(http.MethodGet, runEndpoint, nil)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+	if err := container.ApplyDispatchAuth(request, taskConfig); err != nil {
+		return nil, nil, err
+	}
+	if trusted {
+		request.Header.Set(taskTokenHeader, task.Token)
+	}
+	return client, request, nil
 }
@@ -0,0 +1,131 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCreateTask(t *testing.T) {
+	disabled := false
+	plain := saveTestConfig(t, "/plain", nil)
+	secured := saveTestConfig(t, "/secured", func(c *container.TaskConfig) {
+		c.AuthType, c.AuthPassword = "bearer", "runner-token"
+	})
+	queued := saveTestConfig(t, "/queued", func(c *container.TaskConfig) {
+		c.Enabled, c.QueueWhenDisabled = &disabled, true
+	})
+	stopped := saveTestConfig(t, "/stopped", func(c *container.TaskConfig) {
+		c.Enabled = &disabled
+	})
+
+	tests := []struct {
+		name   string
+		body   CreateTaskRequest
+		status int
+		field  string
+	}{
+		{"dispatch", CreateTaskRequest{TaskType: plain.TaskType, Input: "{}"}, http.StatusOK, ""},
+		{"override endpoint", CreateTaskRequest{TaskType: plain.TaskType, Input: "{}", RunEndpoint: runnerURL + "/other"}, http.StatusOK, ""},
+		{"override endpoint with credentials", CreateTaskRequest{TaskType: secured.TaskType, Input: "{}", RunEndpoint: runnerURL + "/other"}, http.StatusBadRequest, "run_endpoint"},
+		{"queued", CreateTaskRequest{TaskType: queued.TaskType, Input: "{}"}, http.StatusAccepted, ""},
+		{"disabled", CreateTaskRequest{TaskType: stopped.TaskType, Input: "{}"}, http.StatusServiceUnavailable, ""},
+		{"unknown preset", CreateTaskRequest{TaskType: plain.TaskType, PresetID: 1 << 30}, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := doRequest(t, http.MethodPost, "/api/task/create", tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if tt.field != "" && !slices.Contains(fieldErrors(response), tt.field) {
+				t.Fatalf("fields = %v, want %s", fieldErrors(response), tt.field)
+			}
+			if status == http.StatusOK && response["error"] != nil {
+				t.Fatalf("dispatch failed: %v", response)
+			}
+		})
+	}
+}
+
+func TestCreateTaskCredentialsOnlyForConfiguredEndpoint(t *testing.T) {
+	taskConfig := saveTestConfig(t, "/headers", func(c *container.TaskConfig) {
+		c.DispatchHeaders = map[string]string{"X-Env": "prod"}
+	})
+
+	status, response := doRequest(t, http.MethodPost, "/api/task/create", CreateTaskRequest{TaskType: taskConfig.TaskType, Input: "{}"})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, response)
+	}
+	request := testRunner.lastRequest()
+	if request.Header.Get("X-Env") != "prod" {
+		t.Fatalf("configured endpoint did not receive dispatch headers")
+	}
+
+	status, response = doRequest(t, http.MethodPost, "/api/task/create", CreateTaskRequest{TaskType: taskConfig.TaskType, Input: "{}", RunEndpoint: runnerURL + "/other"})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, response)
+	}
+	request = testRunner.lastRequest()
+	if request.URL.Path != "/other" {
+		t.Fatalf("dispatched to %s, want /other", request.URL.Path)
+	}
+	if request.Header.Get("X-Env") != "" {
+		t.Fatal("overridden endpoint received dispatch headers")
+	}
+	if input := request.URL.Query().Get("input"); input == "" || strings.Contains(input, "token=") {
+		t.Fatalf("overridden endpoint received input url %q", input)
+	}
+}
+
+func TestRunAndWaitTask(t *testing.T) {
+	taskConfig := saveTestConfig(t, "/run", nil)
+
+	status, response := doRequest(t, http.MethodPost, "/api/task/run", RunTaskRequest{TaskType: taskConfig.TaskType, Input: "{}", Timeout: "0"})
+	if status != http.StatusAccepted {
+		t.Fatalf("run status = %d, want %d: %v", status, http.StatusAccepted, response)
+	}
+	taskID := responseTaskID(t, response)
+	path := "/api/task/wait/" + strconv.FormatUint(uint64(taskID), 10)
+
+	if status, response = doRequest(t, http.MethodGet, path+"?timeout=0", nil); status != http.StatusAccepted {
+		t.Fatalf("wait status = %d, want %d: %v", status, http.StatusAccepted, response)
+	}
+	if err := container.CallbackTask(taskID, container.TaskStatusSuccess, "done", ""); err != nil {
+		t.Fatal(err)
+	}
+	status, response = doRequest(t, http.MethodGet, path+"?timeout=1s", nil)
+	if status != http.StatusOK || response["status"] != container.TaskStatusSuccess {
+		t.Fatalf("wait = %d %v, want 200 success", status, response)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		task   string // 期望的任务状态，为空时不检查
+	}{
+		{"wait invalid id", http.MethodGet, "/api/task/wait/abc", nil, http.StatusBadRequest, ""},
+		{"wait invalid timeout", http.MethodGet, path + "?timeout=soon", nil, http.StatusBadRequest, ""},
+		{"wait unknown task", http.MethodGet, "/api/task/wait/999999?timeout=0", nil, http.StatusNotFound, ""},
+		{"run invalid timeout", http.MethodPost, "/api/task/run", RunTaskRequest{TaskType: taskConfig.TaskType, Input: "{}", Timeout: "-1"}, http.StatusBadRequest, ""},
+		{"run dispatch failure", http.MethodPost, "/api/task/run", RunTaskRequest{TaskType: taskConfig.TaskType, Input: "{}", RunEndpoint: runnerURL + "/broken", Timeout: "1s"}, http.StatusOK, container.TaskStatusError},
+	}
+	testRunner.failPath("/broken", http.StatusInternalServerError)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := doRequest(t, tt.method, tt.path, tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if tt.task != "" && response["status"] != tt.task {
+				t.Fatalf("task status = %v, want %s", response["status"], tt.task)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterActionRequest 死信批量操作的请求，未指定 dry_run 时只返回匹配结果，需显式传 false 才会执行
+type DeadLetterActionRequest struct {
+	Action string                     `json:"action"`
+	DryRun *bool                      `json:"dry_run"`
+	Filter container.DeadLetterFilter `json:"filter"`
+}
+
+// GetDeadLetters 按任务类型与错误信息分组列出下发或执行失败且未确认的任务
+func GetDeadLetters(ctx *gin.Context) {
+	filter := container.DeadLetterFilter{
+		TaskType:            ctx.Query("task_type"),
+		Statuses:            ctx.QueryArray("status"),
+		IncludeAcknowledged: ctx.Query("include_acknowledged") == "true",
+	}
+	if message, ok := ctx.GetQuery("message"); ok {
+		filter.Message = &message
+	}
+	var err error
+	if filter.StartTime, err = parseTimeParam(ctx.Query("start_time"), false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.EndTime, err = parseTimeParam(ctx.Query("end_time"), true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	groups, err := container.ListDeadLetters(filter)
+	if err != nil {
+		respondQueryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, groups)
+}
+
+// ApplyDeadLetterAction 按筛选条件批量重跑、删除或确认死信任务
+func ApplyDeadLetterAction(ctx *gin.Context) {
+	var req DeadLetterActionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+	result, err := container.ApplyDeadLetterAction(req.Filter, req.Action, dryRun, getOperator(ctx))
+	if err != nil {
+		respondQueryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
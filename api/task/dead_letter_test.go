@@ -0,0 +1,58 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"slices"
+	"testing"
+)
+
+func TestDeadLetters(t *testing.T) {
+	taskConfig := saveTestConfig(t, "/dead-letter", nil)
+	testRunner.failPath("/dead-letter", http.StatusInternalServerError)
+	status, response := doRequest(t, http.MethodPost, "/api/task/create", CreateTaskRequest{TaskType: taskConfig.TaskType, Input: "{}"})
+	if status != http.StatusOK || response["error"] == nil {
+		t.Fatalf("create = %d %v, want dispatch error", status, response)
+	}
+	taskID := responseTaskID(t, response)
+
+	status, response = doRequest(t, http.MethodPost, "/api/task/dead-letter/action", map[string]any{
+		"action": container.DeadLetterActionAcknowledge,
+		"filter": map[string]any{"task_type": taskConfig.TaskType},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("dry run status = %d: %v", status, response)
+	}
+	if response["dry_run"] != true || response["matched"] != float64(1) {
+		t.Fatalf("dry run = %v, want one matched task", response)
+	}
+	if ids, _ := response["task_ids"].([]any); len(ids) != 1 || ids[0] != float64(taskID) {
+		t.Fatalf("task_ids = %v, want [%d]", response["task_ids"], taskID)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		field  string
+	}{
+		{"list", http.MethodGet, "/api/task/dead-letter?task_type=" + taskConfig.TaskType, nil, http.StatusOK, ""},
+		{"list invalid status", http.MethodGet, "/api/task/dead-letter?status=success", nil, http.StatusBadRequest, "status"},
+		{"list invalid time", http.MethodGet, "/api/task/dead-letter?start_time=yesterday", nil, http.StatusBadRequest, ""},
+		{"invalid action", http.MethodPost, "/api/task/dead-letter/action", map[string]any{"action": "retry"}, http.StatusBadRequest, "action"},
+		{"action invalid status", http.MethodPost, "/api/task/dead-letter/action", map[string]any{"action": "delete", "filter": map[string]any{"status": []string{"running"}}}, http.StatusBadRequest, "status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := doRequest(t, tt.method, tt.path, tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if tt.field != "" && !slices.Contains(fieldErrors(response), tt.field) {
+				t.Fatalf("fields = %v, want %s", fieldErrors(response), tt.field)
+			}
+		})
+	}
+}
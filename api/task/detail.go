@@ -27,6 +27,11 @@ func GetTaskByID(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
 		return
 	}
+	task.RerunChain, task.Reruns, err = container.GetRerunChain(task)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, task)
 }
@@ -9,6 +9,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// taskTokenHeader 执行端拉取输入时携带任务令牌的请求头，也可以使用 token 查询参数
+const taskTokenHeader = "X-Task-Token"
+
+// GetTaskInput 获取任务输入，携带有效任务令牌时解密敏感字段并替换密钥引用，否则返回脱敏后的输入
 func GetTaskInput(ctx *gin.Context) {
 	taskID := ctx.Param("task_id")
 	taskIDInt, _ := strconv.Atoi(taskID)
@@ -17,8 +21,21 @@ func GetTaskInput(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{})
 		return
 	}
+	text := container.RedactTaskInput(input.Input, input.SensitiveFields)
+	token := ctx.GetHeader(taskTokenHeader)
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	if container.VerifyTaskToken(input, token) {
+		resolved, err := container.ResolveTaskInput(input)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		text = resolved
+	}
 	var inputObj map[string]interface{} = map[string]interface{}{}
-	if err := json.Unmarshal([]byte(input.Input), &inputObj); err != nil {
+	if err := json.Unmarshal([]byte(text), &inputObj); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
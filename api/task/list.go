@@ -1,17 +1,69 @@
 package task
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"run-task/container"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetTasks 获取任务列表
+//
+// 支持按任务类型、状态（可多个）、创建时间范围、创建人、任务类型标签、输入与结果的子串或 JSON 路径筛选，
+// 以及耗时范围（min_duration、max_duration，秒数或 Go 时长格式）筛选，sort 为 create_time、status 或 duration，order 为 asc 或 desc（默认）。
 func GetTasks(ctx *gin.Context) {
 	// 获取查询参数
-	taskType := ctx.Query("task_type")
+	filter := container.TaskFilter{
+		TaskType:       ctx.Query("task_type"),
+		Statuses:       splitQueryArray(ctx, "status"),
+		Creator:        ctx.Query("creator"),
+		Tags:           splitQueryArray(ctx, "tag"),
+		InputContains:  ctx.Query("input_contains"),
+		ResultContains: ctx.Query("result_contains"),
+		InputPath:      ctx.Query("input_path"),
+		InputValue:     ctx.Query("input_value"),
+		ResultPath:     ctx.Query("result_path"),
+		ResultValue:    ctx.Query("result_value"),
+		Sort:           ctx.Query("sort"),
+		Desc:           ctx.Query("order") != "asc",
+	}
+	var err error
+	if filter.StartTime, err = parseTimeParam(ctx.Query("start_time"), false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.EndTime, err = parseTimeParam(ctx.Query("end_time"), true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MinDuration, err = parseDurationParam(ctx.Query("min_duration")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MaxDuration, err = parseDurationParam(ctx.Query("max_duration")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 传入 cursor 参数（第一页为空值）时使用游标分页，total 为 exact、approx 或 none（默认）
+	if cursor, ok := ctx.GetQuery("cursor"); ok {
+		pageSize := 10
+		if ps, err := strconv.Atoi(ctx.Query("page_size")); err == nil && ps > 0 && ps <= 100 {
+			pageSize = ps
+		}
+		page, err := container.ListTasksByCursor(filter, cursor, pageSize, ctx.DefaultQuery("total", container.TotalModeNone))
+		if err != nil {
+			respondQueryError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, page)
+		return
+	}
 
 	// 手动解析整数参数
 	page := 1
@@ -30,9 +82,9 @@ func GetTasks(ctx *gin.Context) {
 	}
 
 	// 查询任务列表
-	tasks, total, err := container.ListTasks(taskType, page, pageSize)
+	tasks, total, err := container.ListTasks(filter, page, pageSize)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondQueryError(ctx, err)
 		return
 	}
 
@@ -45,3 +97,48 @@ func GetTasks(ctx *gin.Context) {
 		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
 	})
 }
+
+// respondQueryError 输出查询失败的响应，参数校验失败返回 400，数据库错误返回 500
+func respondQueryError(ctx *gin.Context, err error) {
+	var validationErr *container.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validationErr.Fields})
+	case errors.Is(err, container.ErrInvalidCursor):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// splitQueryArray 读取可重复的查询参数，单个参数中也可以用逗号分隔多个值
+func splitQueryArray(ctx *gin.Context, key string) []string {
+	var values []string
+	for _, value := range ctx.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// parseDurationParam 解析耗时参数，支持秒数和 Go 时长格式，如 90 或 1m30s
+func parseDurationParam(value string) (*time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return nil, fmt.Errorf("无效的耗时：%s", value)
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	if duration < 0 {
+		return nil, fmt.Errorf("无效的耗时：%s", value)
+	}
+	return &duration, nil
+}
@@ -0,0 +1,36 @@
+package task
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestQueryErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		field  string
+	}{
+		{"list", "/api/task/list", http.StatusOK, ""},
+		{"list cursor", "/api/task/list?cursor=", http.StatusOK, ""},
+		{"list cursor sort", "/api/task/list?cursor=&sort=duration", http.StatusBadRequest, "sort"},
+		{"list invalid cursor", "/api/task/list?cursor=invalid", http.StatusBadRequest, ""},
+		{"list invalid time", "/api/task/list?start_time=yesterday", http.StatusBadRequest, ""},
+		{"stats", "/api/stats", http.StatusOK, ""},
+		{"stats invalid bucket", "/api/stats?bucket=month", http.StatusBadRequest, "bucket"},
+		{"stats reversed range", "/api/stats?start_time=2024-02-01&end_time=2024-01-01", http.StatusBadRequest, "start_time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := doRequest(t, http.MethodGet, tt.path, nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if tt.field != "" && !slices.Contains(fieldErrors(response), tt.field) {
+				t.Fatalf("fields = %v, want %s", fieldErrors(response), tt.field)
+			}
+		})
+	}
+}
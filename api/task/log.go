@@ -0,0 +1,157 @@
+package task
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"run-task/container"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logFormatText   = "text"
+	logFormatNDJSON = "ndjson"
+	logFormatGzip   = "gzip"
+
+	// 下载日志时每次读取的输出行数
+	logPageSize = 1000
+)
+
+// negotiateLogFormat 优先使用 format 参数，否则根据 Accept 头选择日志格式
+func negotiateLogFormat(ctx *gin.Context) (string, error) {
+	switch format := ctx.Query("format"); format {
+	case logFormatText, logFormatNDJSON, logFormatGzip:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("不支持的日志格式：%s", format)
+	}
+
+	accept := ctx.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "application/x-ndjson"), strings.Contains(accept, "application/ndjson"):
+		return logFormatNDJSON, nil
+	case strings.Contains(accept, "application/gzip"), strings.Contains(accept, "application/x-gzip"):
+		return logFormatGzip, nil
+	default:
+		return logFormatText, nil
+	}
+}
+
+// DownloadTaskLog 以纯文本、NDJSON 或 gzip 格式流式下载任务的完整输出
+func DownloadTaskLog(ctx *gin.Context) {
+	taskID, err := strconv.ParseUint(ctx.Param("task_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+	task := container.GetTask(uint(taskID))
+	if task == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	format, err := negotiateLogFormat(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		contentType string
+		filename    string
+	)
+	switch format {
+	case logFormatNDJSON:
+		contentType, filename = "application/x-ndjson", fmt.Sprintf("task-%d.ndjson", task.ID)
+	case logFormatGzip:
+		contentType, filename = "application/gzip", fmt.Sprintf("task-%d.log.gz", task.ID)
+	default:
+		contentType, filename = "text/plain; charset=utf-8", fmt.Sprintf("task-%d.log", task.ID)
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+
+	var writer io.Writer = ctx.Writer
+	if format == logFormatGzip {
+		gz := gzip.NewWriter(ctx.Writer)
+		defer gz.Close()
+		writer = gz
+	}
+
+	if format == logFormatNDJSON {
+		err = writeNDJSONLog(ctx, writer, task)
+	} else {
+		err = writeTextLog(ctx, writer, task)
+	}
+	if err != nil {
+		// 响应头已经发送，只能中断输出
+		ctx.Error(err)
+	}
+}
+
+// streamTaskOutput 分页读取任务的全部输出并逐页处理
+func streamTaskOutput(ctx *gin.Context, taskID uint, handle func([]container.TaskOutput) error) error {
+	lastID := 0
+	for {
+		outputs, err := container.GetTaskOutput(taskID, lastID, logPageSize)
+		if err != nil {
+			return err
+		}
+		if len(outputs) == 0 {
+			return nil
+		}
+		if err := handle(outputs); err != nil {
+			return err
+		}
+		ctx.Writer.Flush()
+		lastID = int(outputs[len(outputs)-1].ID)
+	}
+}
+
+func writeTextLog(ctx *gin.Context, writer io.Writer, task *container.Task) error {
+	header := []string{
+		fmt.Sprintf("# task_id: %d", task.ID),
+		fmt.Sprintf("# task_type: %s", task.TaskType),
+		fmt.Sprintf("# status: %s", task.Status),
+		fmt.Sprintf("# create_time: %s", task.CreateTime.Format(time.RFC3339)),
+		fmt.Sprintf("# run_endpoint: %s", task.RunEndpoint),
+		fmt.Sprintf("# input: %s", container.RedactTaskInput(task.Input, task.SensitiveFields)),
+		fmt.Sprintf("# result: %s", task.Result),
+		fmt.Sprintf("# message: %s", task.Message),
+		"",
+	}
+	if _, err := io.WriteString(writer, strings.Join(header, "\n")+"\n"); err != nil {
+		return err
+	}
+	return streamTaskOutput(ctx, task.ID, func(outputs []container.TaskOutput) error {
+		for _, output := range outputs {
+			line := fmt.Sprintf("[%s] %s\n", output.CreateTime.Format(time.DateTime), output.Output)
+			if _, err := io.WriteString(writer, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func writeNDJSONLog(ctx *gin.Context, writer io.Writer, task *container.Task) error {
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(gin.H{"task": task}); err != nil {
+		return err
+	}
+	return streamTaskOutput(ctx, task.ID, func(outputs []container.TaskOutput) error {
+		for _, output := range outputs {
+			if err := encoder.Encode(output); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
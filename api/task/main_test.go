@@ -0,0 +1,171 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"run-task/container"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	testRouter *gin.Engine
+	testRunner *fakeRunner
+	runnerURL  string
+	configSeq  atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "run-task-api-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("DATABASE", filepath.Join(dir, "test.db"))
+	os.Setenv("TEMP_DIR", dir)
+	os.Setenv("APP_HOST", "http://run-task.test")
+	container.InitConfig()
+	if err := container.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+	container.RegisterTaskDispatcher(DispatchTask)
+
+	testRunner = &fakeRunner{}
+	runner := httptest.NewServer(testRunner)
+	runnerURL = runner.URL
+	testRouter = newTestRouter()
+
+	code := m.Run()
+	runner.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.POST("/task/config/test", TestTaskConfig)
+	api.POST("/task/create", CreateTask)
+	api.POST("/task/run", RunTask)
+	api.POST("/task/rerun/:task_id", RerunTask)
+	api.GET("/task/dead-letter", GetDeadLetters)
+	api.POST("/task/dead-letter/action", ApplyDeadLetterAction)
+	api.GET("/task/list", GetTasks)
+	api.GET("/task/wait/:task_id", WaitTask)
+	api.GET("/stats", GetTaskStats)
+	return router
+}
+
+// fakeRunner 模拟执行端，记录收到的下发请求并按 status 返回
+type fakeRunner struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	status   map[string]int // 路径到响应状态码，未设置时返回 200
+}
+
+func (r *fakeRunner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req.Clone(req.Context()))
+	if status, ok := r.status[req.URL.Path]; ok {
+		w.WriteHeader(status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// lastRequest 返回执行端最近收到的请求
+func (r *fakeRunner) lastRequest() *http.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return nil
+	}
+	return r.requests[len(r.requests)-1]
+}
+
+// failPath 让执行端对 path 返回 status
+func (r *fakeRunner) failPath(path string, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status == nil {
+		r.status = map[string]int{}
+	}
+	r.status[path] = status
+}
+
+// saveTestConfig 保存一个指向 fakeRunner 的任务类型配置，path 区分不同任务类型的执行地址
+func saveTestConfig(t *testing.T, path string, modify func(*container.TaskConfig)) *container.TaskConfig {
+	t.Helper()
+	taskConfig := &container.TaskConfig{
+		TaskType:    fmt.Sprintf("test%s-%d", strings.ReplaceAll(path, "/", "-"), configSeq.Add(1)),
+		Title:       "test",
+		RunEndpoint: runnerURL + path,
+	}
+	if modify != nil {
+		modify(taskConfig)
+	}
+	if err := container.CreateTaskConfig(taskConfig, "tester"); err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+	return taskConfig
+}
+
+// doRequest 发送请求并解析 JSON 响应，响应不是对象时返回 nil
+func doRequest(t *testing.T, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-User", "tester")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	var response any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: invalid response %q: %v", method, path, w.Body.String(), err)
+	}
+	// 列表接口返回数组，调用方只检查状态码
+	object, _ := response.(map[string]any)
+	return w.Code, object
+}
+
+// responseTaskID 读取响应中的 task_id
+func responseTaskID(t *testing.T, response map[string]any) uint {
+	t.Helper()
+	id, ok := response["task_id"].(float64)
+	if !ok {
+		t.Fatalf("response without task_id: %v", response)
+	}
+	return uint(id)
+}
+
+// fieldErrors 读取校验失败响应中的字段名
+func fieldErrors(response map[string]any) []string {
+	var fields []string
+	items, _ := response["fields"].([]any)
+	for _, item := range items {
+		if field, ok := item.(map[string]any); ok {
+			name, _ := field["field"].(string)
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
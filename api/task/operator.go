@@ -0,0 +1,14 @@
+package task
+
+import "github.com/gin-gonic/gin"
+
+// getOperator 获取当前操作人，优先使用 Basic Auth 用户名，其次使用 X-User 请求头
+//
+// /api 不做认证，两者都由调用方自行填写，可以伪造。操作人只用于记录创建人、修订作者、确认人等信息，
+// 以及区分预设的归属以免误改他人的预设，不能作为权限控制的依据。
+func getOperator(ctx *gin.Context) string {
+	if username, _, ok := ctx.Request.BasicAuth(); ok && username != "" {
+		return username
+	}
+	return ctx.GetHeader("X-User")
+}
@@ -1,7 +1,7 @@
 package task
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"run-task/container"
 	"strconv"
@@ -20,11 +20,42 @@ func GetTaskOutput(ctx *gin.Context) {
 	lastIDInt, _ := strconv.Atoi(lastID)
 	pageSizeInt, _ := strconv.Atoi(pageSize)
 	taskIDInt, _ := strconv.Atoi(taskID)
-	taskOutputs, err := container.GetTaskOutput(uint(taskIDInt), lastIDInt, pageSizeInt)
+	wait, err := parseWaitParam(ctx.Query("wait"), 0)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 传入 cursor 参数时代替 last_id，响应中返回 next_cursor
+	cursor, useCursor := ctx.GetQuery("cursor")
+	if useCursor {
+		if lastIDInt, err = container.ParseTaskOutputCursor(cursor); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var taskOutputs []container.TaskOutput
+	if wait > 0 {
+		// 长轮询：没有新输出时挂起请求，直到有新输出、任务结束或超时
+		taskOutputs, err = container.WaitTaskOutput(ctx.Request.Context(), uint(taskIDInt), lastIDInt, pageSizeInt, wait)
+	} else {
+		taskOutputs, err = container.GetTaskOutput(uint(taskIDInt), lastIDInt, pageSizeInt)
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if taskOutputs == nil {
+		taskOutputs = []container.TaskOutput{}
+	}
+	if useCursor {
+		// 输出会继续增长，下一页游标总是指向已读到的位置
+		if len(taskOutputs) > 0 {
+			lastIDInt = int(taskOutputs[len(taskOutputs)-1].ID)
+		}
+		ctx.JSON(http.StatusOK, gin.H{"outputs": taskOutputs, "next_cursor": container.TaskOutputCursor(lastIDInt)})
+		return
+	}
 	ctx.JSON(http.StatusOK, taskOutputs)
 
 }
@@ -36,9 +67,12 @@ func PostTaskOutput(ctx *gin.Context) {
 		return
 	}
 
-	fmt.Printf("taskID: %s, output: %s\n", taskID, string(output))
 	taskIDInt, _ := strconv.Atoi(taskID)
 	err = container.CreateTaskOutput(uint(taskIDInt), string(output))
+	if errors.Is(err, container.ErrTaskNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
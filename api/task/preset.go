@@ -0,0 +1,154 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"run-task/container"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskPresets 获取当前操作人可见的预设，按当前表单标记已失效的预设
+func GetTaskPresets(ctx *gin.Context) {
+	presets, err := container.ListTaskPresets(ctx.Query("task_type"), getOperator(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, presets)
+}
+
+// GetTaskPreset 获取单个预设
+func GetTaskPreset(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的预设ID"})
+		return
+	}
+	preset, err := container.GetTaskPreset(uint(id), getOperator(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, preset)
+}
+
+// CreateTaskPreset 创建预设，创建人为当前操作人
+func CreateTaskPreset(ctx *gin.Context) {
+	var preset container.TaskPreset
+	if err := ctx.ShouldBindJSON(&preset); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if preset.TaskType == "" || preset.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "task_type和name不能为空"})
+		return
+	}
+	if container.GetTaskConfig(preset.TaskType) == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": container.ErrTaskConfigNotFound.Error()})
+		return
+	}
+	preset.ID = 0
+	if preset.Owner = getOperator(ctx); preset.Owner == "" {
+		respondPresetError(ctx, container.ErrTaskPresetNoOperator)
+		return
+	}
+	if err := container.SaveTaskPreset(&preset); err != nil {
+		respondPresetError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, preset)
+}
+
+// UpdateTaskPreset 更新预设的名称、输入和共享状态，只有创建人可以修改
+func UpdateTaskPreset(ctx *gin.Context) {
+	var req container.TaskPreset
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	operator := getOperator(ctx)
+	if operator == "" {
+		respondPresetError(ctx, container.ErrTaskPresetNoOperator)
+		return
+	}
+	preset, err := container.GetTaskPreset(req.ID, operator)
+	if err != nil {
+		respondPresetError(ctx, err)
+		return
+	}
+	if !preset.OwnedBy(operator) {
+		respondPresetError(ctx, container.ErrTaskPresetForbidden)
+		return
+	}
+	preset.Name, preset.Input, preset.Shared = req.Name, req.Input, req.Shared
+	if err := container.SaveTaskPreset(preset); err != nil {
+		respondPresetError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, preset)
+}
+
+// DeleteTaskPreset 删除预设，只有创建人可以删除
+func DeleteTaskPreset(ctx *gin.Context) {
+	var req container.TaskPreset
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	operator := getOperator(ctx)
+	if operator == "" {
+		respondPresetError(ctx, container.ErrTaskPresetNoOperator)
+		return
+	}
+	preset, err := container.GetTaskPreset(req.ID, operator)
+	if err != nil {
+		respondPresetError(ctx, err)
+		return
+	}
+	if !preset.OwnedBy(operator) {
+		respondPresetError(ctx, container.ErrTaskPresetForbidden)
+		return
+	}
+	if err := container.DeleteTaskPreset(req.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "预设删除成功"})
+}
+
+func respondPresetError(ctx *gin.Context, err error) {
+	var validationErr *container.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": validationErr.Fields})
+	case errors.Is(err, container.ErrTaskPresetNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, container.ErrTaskPresetNoOperator):
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, container.ErrTaskPresetForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// applyPreset 将预设输入与请求中的输入合并，请求中的字段优先
+func applyPreset(ctx *gin.Context, task *container.Task, presetID uint) error {
+	if presetID == 0 {
+		return nil
+	}
+	preset, err := container.GetTaskPreset(presetID, getOperator(ctx))
+	if err != nil {
+		return err
+	}
+	if task.TaskType == "" {
+		task.TaskType = preset.TaskType
+	}
+	if task.TaskType != preset.TaskType {
+		return &container.ValidationError{Fields: []container.FieldError{{Field: "preset_id", Message: "预设不属于该任务类型"}}}
+	}
+	task.Input, err = container.MergePresetInput(preset.Input, task.Input)
+	return err
+}
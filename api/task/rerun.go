@@ -0,0 +1,85 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"run-task/container"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CloneTaskRequest 复制任务的请求，input 中的字段覆盖原任务的输入
+type CloneTaskRequest struct {
+	Input string `json:"input"`
+}
+
+// parseTaskIDParam 解析路径中的任务ID并读取任务，失败时已输出响应
+func parseTaskIDParam(ctx *gin.Context) *container.Task {
+	taskID, err := strconv.ParseUint(ctx.Param("task_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return nil
+	}
+	task := container.GetTask(uint(taskID))
+	if task == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": container.ErrTaskNotFound.Error()})
+		return nil
+	}
+	return task
+}
+
+// RerunTask 使用原任务的输入与配置重新创建并下发任务
+func RerunTask(ctx *gin.Context) {
+	original := parseTaskIDParam(ctx)
+	if original == nil {
+		return
+	}
+	status, err := container.CheckTaskTypeEnabled(container.GetTaskConfig(original.TaskType))
+	if err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	task, err := container.RerunTask(original.ID, status, getOperator(ctx))
+	if err != nil {
+		if errors.Is(err, container.ErrTaskNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondCreated(ctx, task)
+}
+
+// CloneTask 以原任务的输入为基础创建新任务，按当前配置校验并下发
+func CloneTask(ctx *gin.Context) {
+	original := parseTaskIDParam(ctx)
+	if original == nil {
+		return
+	}
+	var req CloneTaskRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	input, err := container.CloneTaskInput(original, req.Input)
+	if err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	task := &container.Task{
+		TaskType: original.TaskType,
+		Input:    input,
+		Creator:  getOperator(ctx),
+		ParentID: original.ID,
+		Origin:   container.TaskOriginClone,
+	}
+	if err := createTask(task); err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	respondCreated(ctx, task)
+}
@@ -0,0 +1,57 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"strconv"
+	"testing"
+)
+
+func TestRerunTask(t *testing.T) {
+	taskConfig := saveTestConfig(t, "/rerun", func(c *container.TaskConfig) {
+		c.DispatchHeaders = map[string]string{"X-Env": "v1"}
+	})
+	status, response := doRequest(t, http.MethodPost, "/api/task/create", CreateTaskRequest{TaskType: taskConfig.TaskType, Input: "{}"})
+	if status != http.StatusOK {
+		t.Fatalf("create status = %d: %v", status, response)
+	}
+	originalID := responseTaskID(t, response)
+	revision := response["config_revision"]
+
+	updated := *taskConfig
+	updated.DispatchHeaders = map[string]string{"X-Env": "v2"}
+	if err := container.UpdateTaskConfig(taskConfig.TaskType, &updated, "tester"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重跑使用原任务的配置修订，而不是当前配置
+	status, response = doRequest(t, http.MethodPost, "/api/task/rerun/"+strconv.FormatUint(uint64(originalID), 10), nil)
+	if status != http.StatusOK || response["error"] != nil {
+		t.Fatalf("rerun = %d %v, want 200", status, response)
+	}
+	if responseTaskID(t, response) == originalID {
+		t.Fatal("rerun returned the original task")
+	}
+	if response["config_revision"] != revision {
+		t.Fatalf("config_revision = %v, want %v", response["config_revision"], revision)
+	}
+	if header := testRunner.lastRequest().Header.Get("X-Env"); header != "v1" {
+		t.Fatalf("rerun dispatched with X-Env %q, want v1", header)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid id", "/api/task/rerun/abc", http.StatusBadRequest},
+		{"unknown task", "/api/task/rerun/999999", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, response := doRequest(t, http.MethodPost, tt.path, nil); status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+		})
+	}
+}
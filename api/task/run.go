@@ -0,0 +1,74 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RunTaskRequest 同步运行任务的请求
+type RunTaskRequest struct {
+	TaskType    string `json:"task_type"`
+	Input       string `json:"input"`
+	RunEndpoint string `json:"run_endpoint"`
+	PresetID    uint   `json:"preset_id"`
+	Timeout     string `json:"timeout"`     // 等待时间，如 30 或 30s，默认 30 秒
+	OutputTail  int    `json:"output_tail"` // 返回最后多少行输出，0 表示不返回
+}
+
+// RunTask 创建任务并等待其结束后返回结果，超时时返回 202 和任务ID
+func RunTask(ctx *gin.Context) {
+	var req RunTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	timeout, err := parseWaitParam(req.Timeout, 30*time.Second)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	newTask := &container.Task{TaskType: req.TaskType, Input: req.Input, RunEndpoint: req.RunEndpoint, Creator: getOperator(ctx)}
+	if err := applyPreset(ctx, newTask, req.PresetID); err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	if err := createTask(newTask); err != nil {
+		respondCreateError(ctx, err)
+		return
+	}
+	taskID := newTask.ID
+	// 下发失败时任务已被标记为 error，按结束状态返回；排队的任务等待重新启用后下发
+	if newTask.Status != container.TaskStatusQueued {
+		DispatchTask(taskID)
+	}
+
+	task, done := container.WaitTask(ctx.Request.Context(), taskID, timeout)
+	if task == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在", "task_id": taskID})
+		return
+	}
+	if !done {
+		ctx.JSON(http.StatusAccepted, gin.H{"task_id": taskID, "status": task.Status})
+		return
+	}
+
+	response := gin.H{
+		"task_id": taskID,
+		"status":  task.Status,
+		"result":  task.Result,
+		"message": task.Message,
+	}
+	if req.OutputTail > 0 {
+		outputs, err := container.GetTaskOutputTail(taskID, req.OutputTail)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "task_id": taskID})
+			return
+		}
+		response["output"] = outputs
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"fmt"
+	"net/http"
+	"run-task/container"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchTasks 全文搜索任务输入、结果与输出
+func SearchTasks(ctx *gin.Context) {
+	query := ctx.Query("q")
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "搜索内容不能为空"})
+		return
+	}
+
+	filter := container.SearchFilter{
+		Query:    query,
+		TaskType: ctx.Query("task_type"),
+	}
+	var err error
+	if filter.StartTime, err = parseTimeParam(ctx.Query("start_time"), false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.EndTime, err = parseTimeParam(ctx.Query("end_time"), true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := 1
+	pageSize := 10
+	if p, err := strconv.Atoi(ctx.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(ctx.Query("page_size")); err == nil && ps > 0 && ps <= 100 {
+		pageSize = ps
+	}
+
+	results, total, err := container.SearchTasks(filter, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"tasks":       results,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// parseTimeParam 解析时间查询参数，支持 RFC3339、"2006-01-02 15:04:05" 和 "2006-01-02"
+//
+// endOfDay 为 true 时，只有日期的值表示当天结束，用作不包含的结束时间
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	return nil, fmt.Errorf("无效的时间：%s", value)
+}
@@ -0,0 +1,65 @@
+package task
+
+import (
+	"errors"
+	"net/http"
+	"run-task/container"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveTaskSecretRequest 保存密钥的请求，task_type 为空时为全局密钥
+type SaveTaskSecretRequest struct {
+	Name     string `json:"name"`
+	TaskType string `json:"task_type"`
+	Value    string `json:"value"`
+}
+
+// GetTaskSecrets 列出密钥名称与作用域，不返回密钥的值
+func GetTaskSecrets(ctx *gin.Context) {
+	secrets, err := container.ListTaskSecrets(ctx.Query("task_type"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, secrets)
+}
+
+// SaveTaskSecret 创建或覆盖密钥
+func SaveTaskSecret(ctx *gin.Context) {
+	var request SaveTaskSecretRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.TaskType != "" && container.GetTaskConfig(request.TaskType) == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": container.ErrTaskConfigNotFound.Error()})
+		return
+	}
+	if err := container.SaveTaskSecret(request.Name, request.TaskType, request.Value); err != nil {
+		switch {
+		case errors.Is(err, container.ErrTaskSecretInvalid):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, container.ErrSecretKeyMissing):
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"name": request.Name, "task_type": request.TaskType})
+}
+
+// DeleteTaskSecret 删除密钥
+func DeleteTaskSecret(ctx *gin.Context) {
+	var request SaveTaskSecretRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := container.DeleteTaskSecret(request.Name, request.TaskType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "密钥删除成功"})
+}
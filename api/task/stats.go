@@ -0,0 +1,55 @@
+package task
+
+import (
+	"net/http"
+	"run-task/container"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultStatsRange 未指定开始时间时各粒度默认统计的时间跨度
+var defaultStatsRange = map[string]time.Duration{
+	container.StatsBucketHour: 24 * time.Hour,
+	container.StatsBucketDay:  30 * 24 * time.Hour,
+	container.StatsBucketWeek: 12 * 7 * 24 * time.Hour,
+}
+
+// GetTaskStats 按任务类型与时间分桶（hour、day 或 week）统计任务数量、成功率、耗时与排队等待
+func GetTaskStats(ctx *gin.Context) {
+	filter := container.StatsFilter{
+		TaskType: ctx.Query("task_type"),
+		Bucket:   ctx.DefaultQuery("bucket", container.StatsBucketDay),
+		EndTime:  time.Now(),
+	}
+	startTime, err := parseTimeParam(ctx.Query("start_time"), false)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	endTime, err := parseTimeParam(ctx.Query("end_time"), true)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if endTime != nil {
+		filter.EndTime = *endTime
+	}
+	if startTime != nil {
+		filter.StartTime = *startTime
+	} else {
+		filter.StartTime = filter.EndTime.Add(-defaultStatsRange[filter.Bucket])
+	}
+
+	buckets, err := container.GetTaskStats(filter)
+	if err != nil {
+		respondQueryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"bucket":     filter.Bucket,
+		"start_time": filter.StartTime,
+		"end_time":   filter.EndTime,
+		"stats":      buckets,
+	})
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"fmt"
+	"net/http"
+	"run-task/container"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 长轮询等待时间的上限
+const maxWaitTimeout = 10 * time.Minute
+
+// parseWaitParam 解析等待时间，支持秒数（如 30）或时长（如 30s、2m），超过上限时取上限
+func parseWaitParam(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, fmt.Errorf("无效的等待时间：%s", value)
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("无效的等待时间：%s", value)
+	}
+	return min(wait, maxWaitTimeout), nil
+}
+
+// WaitTask 等待任务结束后返回任务详情，超时时返回 202 和当前状态
+func WaitTask(ctx *gin.Context) {
+	taskID, err := strconv.ParseUint(ctx.Param("task_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+	timeout, err := parseWaitParam(ctx.Query("timeout"), 30*time.Second)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, done := container.WaitTask(ctx.Request.Context(), uint(taskID), timeout)
+	if task == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if !done {
+		ctx.JSON(http.StatusAccepted, task)
+		return
+	}
+	ctx.JSON(http.StatusOK, task)
+}
@@ -2,6 +2,7 @@ package container
 
 import (
 	"log"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -14,6 +15,37 @@ type AppConfig struct {
 	AppHost     string `env:"APP_HOST" envDefault:"http://localhost:8080"`
 	Username    string `env:"USERNAME" envDefault:"admin"`
 	Password    string `env:"PASSWORD" envDefault:"123456"`
+
+	// SQLite 等待锁的超时时间（毫秒）
+	DBBusyTimeout int `env:"DB_BUSY_TIMEOUT" envDefault:"5000"`
+	// 任务输出缓冲：每个任务在内存中保留的最近行数、批量落库的行数阈值与时间阈值
+	OutputBufferSize    int           `env:"OUTPUT_BUFFER_SIZE" envDefault:"1000"`
+	OutputFlushSize     int           `env:"OUTPUT_FLUSH_SIZE" envDefault:"100"`
+	OutputFlushInterval time.Duration `env:"OUTPUT_FLUSH_INTERVAL" envDefault:"1s"`
+
+	// 任务输出保留策略，0 表示不限制；TaskConfig 中的同名设置优先
+	OutputMaxAge          time.Duration `env:"OUTPUT_MAX_AGE" envDefault:"0"`
+	OutputMaxLines        int           `env:"OUTPUT_MAX_LINES" envDefault:"0"`
+	OutputArchive         bool          `env:"OUTPUT_ARCHIVE" envDefault:"true"`
+	OutputCompactInterval time.Duration `env:"OUTPUT_COMPACT_INTERVAL" envDefault:"1h"`
+
+	// 声明式配置目录，目录中的 TaskConfig YAML 文件在启动时同步到数据库
+	ConfigDir string `env:"CONFIG_DIR"`
+	// 配置目录的轮询间隔，0 表示只在启动时同步
+	ConfigDirWatchInterval time.Duration `env:"CONFIG_DIR_WATCH_INTERVAL" envDefault:"0"`
+
+	// 下发任务的默认超时，任务配置中未设置时使用
+	DispatchTimeout time.Duration `env:"DISPATCH_TIMEOUT" envDefault:"60s"`
+
+	// 统计预聚合：开启后按小时汇总早于 StatsRollupDelay 的任务，统计接口对这部分历史直接读取汇总
+	StatsRollup         bool          `env:"STATS_ROLLUP" envDefault:"false"`
+	StatsRollupInterval time.Duration `env:"STATS_ROLLUP_INTERVAL" envDefault:"1h"`
+	StatsRollupDelay    time.Duration `env:"STATS_ROLLUP_DELAY" envDefault:"24h"`
+	// 汇总最多等待未结束任务的时长，创建超过该时长仍未结束的任务按当前状态汇总，避免卡住的任务使汇总一直停滞
+	StatsRollupMaxPending time.Duration `env:"STATS_ROLLUP_MAX_PENDING" envDefault:"72h"`
+
+	// 加密密钥库与敏感字段使用的主密钥，未配置时无法使用密钥库，敏感字段明文保存
+	SecretKey string `env:"SECRET_KEY"`
 }
 
 var (
@@ -26,6 +58,9 @@ func InitConfig() error {
 	if err := env.Parse(cfg); err != nil {
 		log.Fatalf("Failed to parse environment variables: %v", err)
 	}
+	if cfg.SecretKey == "" {
+		log.Printf("SECRET_KEY is not set, secrets are unavailable and sensitive task input is stored unencrypted")
+	}
 	return nil
 }
 
@@ -0,0 +1,226 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+// 配置包导入模式
+const (
+	ImportModeMerge     = "merge"     // 新建不存在的配置，已有配置只更新包中出现的字段
+	ImportModeOverwrite = "overwrite" // 新建不存在的配置，已有配置整体替换
+)
+
+// 导入结果中的操作
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionInvalid   = "invalid"
+)
+
+// TaskConfigBundle 导出导入用的配置包
+type TaskConfigBundle struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Configs    []*TaskConfig `json:"configs"`
+
+	// presentFields 每个配置在包中出现的字段，merge 模式只合并这些字段，为空时视为全部出现
+	presentFields []map[string]bool
+}
+
+// ParseTaskConfigBundle 解析 YAML 或 JSON 格式的配置包，并记录每个配置出现的字段
+func ParseTaskConfigBundle(data []byte) (*TaskConfigBundle, error) {
+	var bundle TaskConfigBundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Configs []map[string]interface{} `json:"configs"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, fields := range raw.Configs {
+		present := map[string]bool{}
+		for name := range fields {
+			present[name] = true
+		}
+		bundle.presentFields = append(bundle.presentFields, present)
+	}
+	return &bundle, nil
+}
+
+// fieldsOf 返回第 i 个配置在包中出现的字段
+func (b *TaskConfigBundle) fieldsOf(i int) map[string]bool {
+	if i < len(b.presentFields) {
+		return b.presentFields[i]
+	}
+	return nil
+}
+
+// ImportResult 单个配置的导入结果
+type ImportResult struct {
+	TaskType string         `json:"task_type"`
+	Action   string         `json:"action"`
+	Changes  []ConfigChange `json:"changes,omitempty"`
+	Errors   []FieldError   `json:"errors,omitempty"`
+}
+
+// ExportTaskConfigs 导出配置，taskTypes 为空时导出全部
+func ExportTaskConfigs(taskTypes []string) (*TaskConfigBundle, error) {
+	var taskConfigs []*TaskConfig
+	query := db.Order("task_type")
+	if len(taskTypes) > 0 {
+		query = query.Where("task_type IN ?", taskTypes)
+	}
+	if err := query.Find(&taskConfigs).Error; err != nil {
+		return nil, err
+	}
+	// YAML 导出不经过 MarshalJSON，这里直接脱敏
+	for _, taskConfig := range taskConfigs {
+		taskConfig.redactCredentials()
+	}
+	return &TaskConfigBundle{Version: 1, ExportedAt: time.Now(), Configs: taskConfigs}, nil
+}
+
+// mergeTaskConfig 将 incoming 中出现在 present 里的字段合并到 existing 的副本上，present 为 nil 时合并全部字段
+//
+// 出现的字段即使是空字符串、0 或 false 也会覆盖已有的值。
+func mergeTaskConfig(existing, incoming *TaskConfig, present map[string]bool) *TaskConfig {
+	// 不经过 MarshalJSON，避免凭据被脱敏
+	type plainTaskConfig TaskConfig
+	merged := *existing
+	data, _ := json.Marshal(plainTaskConfig(*incoming))
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	for name := range fields {
+		if present != nil && !present[name] {
+			delete(fields, name)
+		}
+	}
+	// 解码到已有的 map 会合并键，出现时整体替换
+	if _, ok := fields["dispatch_headers"]; ok {
+		merged.DispatchHeaders = nil
+	}
+	data, _ = json.Marshal(fields)
+	json.Unmarshal(data, &merged)
+	return &merged
+}
+
+// redactedCredentialErrors 检查导出时被脱敏的旧明文凭据，这类配置需先改为密钥引用再重新导出
+func redactedCredentialErrors(taskConfig *TaskConfig) []FieldError {
+	const message = "导出时已脱敏的明文凭据，请在原环境中改为 ${secret:NAME} 引用后重新导出"
+	var errs []FieldError
+	if taskConfig.AuthPassword == RedactedValue {
+		errs = append(errs, FieldError{Field: "auth_password", Message: message})
+	}
+	if taskConfig.ClientKey == RedactedValue {
+		errs = append(errs, FieldError{Field: "client_key", Message: message})
+	}
+	return errs
+}
+
+// ImportTaskConfigs 导入配置包
+//
+// 任意配置校验失败时不写入任何配置，全部配置在同一事务中写入；dryRun 为 true 时只返回将要执行的操作。
+// 第二个返回值表示是否全部通过校验。导出的配置包中早期保存的明文凭据已脱敏为 ******，
+// 这类配置导入时报告为 invalid，需要先改为密钥引用。
+func ImportTaskConfigs(bundle *TaskConfigBundle, mode string, dryRun bool, author string) ([]*ImportResult, bool, error) {
+	if mode != ImportModeMerge && mode != ImportModeOverwrite {
+		return nil, false, fmt.Errorf("不支持的导入模式：%s", mode)
+	}
+
+	type plan struct {
+		result *ImportResult
+		config *TaskConfig
+	}
+	var plans []plan
+	valid := true
+	seen := map[string]bool{}
+	for i, incoming := range bundle.Configs {
+		result := &ImportResult{TaskType: incoming.TaskType}
+		if seen[incoming.TaskType] {
+			result.Action = ImportActionInvalid
+			result.Errors = []FieldError{{Field: "task_type", Message: "配置包中重复"}}
+			plans, valid = append(plans, plan{result: result}), false
+			continue
+		}
+		seen[incoming.TaskType] = true
+
+		if errs := redactedCredentialErrors(incoming); len(errs) > 0 {
+			result.Action, result.Errors = ImportActionInvalid, errs
+			plans, valid = append(plans, plan{result: result}), false
+			continue
+		}
+
+		existing := GetTaskConfig(incoming.TaskType)
+		target := incoming
+		if existing != nil && mode == ImportModeMerge {
+			target = mergeTaskConfig(existing, incoming, bundle.fieldsOf(i))
+		}
+		if err := ValidateTaskConfig(target); err != nil {
+			result.Action = ImportActionInvalid
+			if validationErr, ok := err.(*ValidationError); ok {
+				result.Errors = validationErr.Fields
+			}
+			plans, valid = append(plans, plan{result: result}), false
+			continue
+		}
+
+		switch {
+		case existing == nil:
+			result.Action = ImportActionCreate
+			result.Changes = DiffTaskConfig(nil, target)
+		default:
+			result.Changes = DiffTaskConfig(existing, target)
+			result.Action = ImportActionUpdate
+			if len(result.Changes) == 0 {
+				result.Action = ImportActionUnchanged
+			}
+		}
+		plans = append(plans, plan{result: result, config: target})
+	}
+
+	results := make([]*ImportResult, 0, len(plans))
+	for _, p := range plans {
+		results = append(results, p.result)
+	}
+	if dryRun || !valid {
+		return results, valid, nil
+	}
+
+	var enabled []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range plans {
+			taskConfig := *p.config
+			var err error
+			switch p.result.Action {
+			case ImportActionCreate:
+				taskConfig.Revision = 0
+				err = insertTaskConfig(tx, &taskConfig, author)
+			case ImportActionUpdate:
+				err = applyTaskConfig(tx, taskConfig.TaskType, &taskConfig, author, RevisionActionUpdate)
+				if taskConfig.IsEnabled() {
+					enabled = append(enabled, taskConfig.TaskType)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("导入 %s 失败：%w", taskConfig.TaskType, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return results, valid, err
+	}
+	// 重新启用后下发停用期间排队的任务
+	for _, taskType := range enabled {
+		go DispatchQueuedTasks(taskType)
+	}
+	return results, valid, nil
+}
@@ -0,0 +1,259 @@
+package container
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// 配置目录同步时记录修订使用的操作人
+const configDirAuthor = "config-dir"
+
+// 配置目录与数据库的差异状态
+const (
+	DriftStatusInSync   = "in_sync"
+	DriftStatusDrifted  = "drifted"
+	DriftStatusMissing  = "missing"  // 文件中有，数据库中没有
+	DriftStatusOrphaned = "orphaned" // 数据库中标记为受管理，但文件已不存在
+	DriftStatusInvalid  = "invalid"  // 文件无法解析或配置校验失败
+)
+
+// ConfigDrift 单个配置在文件与数据库之间的差异
+type ConfigDrift struct {
+	TaskType string         `json:"task_type"`
+	Source   string         `json:"source"`
+	Status   string         `json:"status"`
+	Changes  []ConfigChange `json:"changes,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// configFile 配置目录中解析出的一个配置
+type configFile struct {
+	Source string
+	Config *TaskConfig
+	Err    error
+}
+
+// loadConfigDir 读取目录下所有 .yaml/.yml 文件，文件可以是单个 TaskConfig 或带 configs 的配置包
+func loadConfigDir(dir string) ([]*configFile, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	var files []*configFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			files = append(files, &configFile{Source: path, Err: err})
+			continue
+		}
+		var bundle TaskConfigBundle
+		if err := yaml.Unmarshal(data, &bundle); err == nil && len(bundle.Configs) > 0 {
+			for _, taskConfig := range bundle.Configs {
+				files = append(files, &configFile{Source: path, Config: taskConfig})
+			}
+			continue
+		}
+		var taskConfig TaskConfig
+		if err := yaml.Unmarshal(data, &taskConfig); err != nil {
+			files = append(files, &configFile{Source: path, Err: err})
+			continue
+		}
+		files = append(files, &configFile{Source: path, Config: &taskConfig})
+	}
+
+	// 同一个 task_type 出现在多个文件中时只使用第一个
+	seen := map[string]string{}
+	for _, file := range files {
+		if file.Config == nil {
+			continue
+		}
+		if file.Err = ValidateTaskConfig(file.Config); file.Err != nil {
+			continue
+		}
+		if source, ok := seen[file.Config.TaskType]; ok {
+			file.Err = fmt.Errorf("task_type %s 已在 %s 中定义", file.Config.TaskType, source)
+			continue
+		}
+		seen[file.Config.TaskType] = file.Source
+	}
+	return files, nil
+}
+
+// SyncConfigDir 将配置目录中的配置写入数据库并标记为受管理，文件已删除的配置恢复为可编辑
+//
+// 文件无法解析、校验失败或写入失败时保留数据库中的配置与受管理状态，错误通过 GetConfigDrift 报告。
+func SyncConfigDir() error {
+	dir := GetConfig().ConfigDir
+	if dir == "" {
+		return nil
+	}
+	files, err := loadConfigDir(dir)
+	if err != nil {
+		return err
+	}
+
+	managed := map[string]bool{}
+	failedSources := map[string]bool{}
+	for _, file := range files {
+		if file.Err != nil {
+			log.Printf("skip config file %s: %v", file.Source, file.Err)
+			failedSources[file.Source] = true
+			continue
+		}
+		bundle := &TaskConfigBundle{Configs: []*TaskConfig{file.Config}}
+		if _, _, err := ImportTaskConfigs(bundle, ImportModeOverwrite, false, configDirAuthor); err != nil {
+			log.Printf("sync config %s from %s failed: %v", file.Config.TaskType, file.Source, err)
+			failedSources[file.Source] = true
+			continue
+		}
+		err := db.Model(&TaskConfig{}).Where("task_type = ?", file.Config.TaskType).
+			Updates(map[string]interface{}{"managed": true, "managed_source": file.Source}).Error
+		if err != nil {
+			return err
+		}
+		managed[file.Config.TaskType] = true
+	}
+
+	var existing []*TaskConfig
+	if err := db.Where("managed = ?", true).Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, taskConfig := range existing {
+		if managed[taskConfig.TaskType] || failedSources[taskConfig.ManagedSource] {
+			continue
+		}
+		log.Printf("config file of %s removed, config is editable again", taskConfig.TaskType)
+		err := db.Model(&TaskConfig{}).Where("task_type = ?", taskConfig.TaskType).
+			Updates(map[string]interface{}{"managed": false, "managed_source": ""}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configDirSignature 返回目录下配置文件的路径、大小与修改时间，用于检测变化
+func configDirSignature(dir string) string {
+	var parts []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		}
+		return nil
+	})
+	return strings.Join(parts, "|")
+}
+
+// StartConfigDirSync 启动时同步配置目录，配置了轮询间隔时在文件变化后重新同步
+func StartConfigDirSync() error {
+	cfg := GetConfig()
+	if cfg.ConfigDir == "" {
+		return nil
+	}
+	if err := SyncConfigDir(); err != nil {
+		return err
+	}
+	if cfg.ConfigDirWatchInterval <= 0 {
+		return nil
+	}
+	go func() {
+		signature := configDirSignature(cfg.ConfigDir)
+		ticker := time.NewTicker(cfg.ConfigDirWatchInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			current := configDirSignature(cfg.ConfigDir)
+			if current == signature {
+				continue
+			}
+			signature = current
+			if err := SyncConfigDir(); err != nil {
+				log.Printf("sync config dir failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// GetConfigDrift 比较配置目录与数据库中的配置
+func GetConfigDrift() ([]*ConfigDrift, error) {
+	dir := GetConfig().ConfigDir
+	if dir == "" {
+		return nil, fmt.Errorf("未配置 CONFIG_DIR")
+	}
+	files, err := loadConfigDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := []*ConfigDrift{}
+	inFiles := map[string]bool{}
+	invalidSources := map[string]bool{}
+	for _, file := range files {
+		drift := &ConfigDrift{Source: file.Source}
+		if file.Config != nil {
+			drift.TaskType = file.Config.TaskType
+		}
+		drifts = append(drifts, drift)
+		if file.Err != nil {
+			drift.Status, drift.Error = DriftStatusInvalid, file.Err.Error()
+			invalidSources[file.Source] = true
+			continue
+		}
+		inFiles[file.Config.TaskType] = true
+
+		existing := GetTaskConfig(file.Config.TaskType)
+		if existing == nil {
+			drift.Status = DriftStatusMissing
+			continue
+		}
+		drift.Changes = DiffTaskConfig(existing, file.Config)
+		drift.Status = DriftStatusInSync
+		if len(drift.Changes) > 0 || !existing.Managed {
+			drift.Status = DriftStatusDrifted
+		}
+	}
+
+	var managed []*TaskConfig
+	if err := db.Where("managed = ?", true).Find(&managed).Error; err != nil {
+		return nil, err
+	}
+	for _, taskConfig := range managed {
+		// 文件仍在但无法解析时已按 invalid 报告，补上该文件管理的任务类型
+		if invalidSources[taskConfig.ManagedSource] {
+			for _, drift := range drifts {
+				if drift.Status == DriftStatusInvalid && drift.Source == taskConfig.ManagedSource && drift.TaskType == "" {
+					drift.TaskType = taskConfig.TaskType
+					break
+				}
+			}
+			continue
+		}
+		if !inFiles[taskConfig.TaskType] {
+			drifts = append(drifts, &ConfigDrift{
+				TaskType: taskConfig.TaskType,
+				Source:   taskConfig.ManagedSource,
+				Status:   DriftStatusOrphaned,
+			})
+		}
+	}
+	return drifts, nil
+}
@@ -0,0 +1,206 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 配置修订的操作类型
+const (
+	RevisionActionBaseline = "baseline"
+	RevisionActionCreate   = "create"
+	RevisionActionUpdate   = "update"
+	RevisionActionRollback = "rollback"
+	RevisionActionDelete   = "delete"
+)
+
+// TaskConfigRevision TaskConfig 的历史修订，对应 task_config_revision 表
+type TaskConfigRevision struct {
+	ID         uint      `gorm:"autoIncrement;column:id" json:"id"`
+	TaskType   string    `gorm:"column:task_type;index" json:"task_type"`
+	Revision   int       `gorm:"column:revision" json:"revision"`
+	Action     string    `gorm:"column:action" json:"action"`
+	Author     string    `gorm:"column:author" json:"author"`
+	Config     string    `gorm:"column:config" json:"config"` // 修订后的完整配置 JSON
+	Diff       string    `gorm:"column:diff" json:"diff"`     // 与上一修订相比的字段变更 JSON
+	CreateTime time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+func (TaskConfigRevision) TableName() string {
+	return "task_config_revision"
+}
+
+// ConfigChange 单个字段的变更
+type ConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// configFields 将配置转换为字段表，忽略修订号和配置目录的管理状态
+//
+// 未设置的 enabled 按启用处理，空的标签与请求头视为未设置，文件中省略这些字段时不产生差异。
+func configFields(taskConfig *TaskConfig) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if taskConfig == nil {
+		return fields
+	}
+	normalized := *taskConfig
+	enabled := normalized.IsEnabled()
+	normalized.Enabled = &enabled
+	if len(normalized.Tags) == 0 {
+		normalized.Tags = nil
+	}
+	if len(normalized.DispatchHeaders) == 0 {
+		normalized.DispatchHeaders = nil
+	}
+	data, _ := json.Marshal(normalized)
+	json.Unmarshal(data, &fields)
+	delete(fields, "revision")
+	delete(fields, "managed")
+	delete(fields, "managed_source")
+	return fields
+}
+
+// DiffTaskConfig 比较两个配置，返回按字段名排序的变更
+func DiffTaskConfig(oldConfig, newConfig *TaskConfig) []ConfigChange {
+	oldFields, newFields := configFields(oldConfig), configFields(newConfig)
+	var names []string
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	changes := []ConfigChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(oldFields[name], newFields[name]) {
+			changes = append(changes, ConfigChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+	return changes
+}
+
+// saveTaskConfigRevision 记录一次配置修订，并更新配置上的当前修订号
+func saveTaskConfigRevision(tx *gorm.DB, oldConfig, newConfig *TaskConfig, author string, action string) (*TaskConfigRevision, error) {
+	taskType := newConfig.TaskType
+	var last int
+	if err := tx.Model(&TaskConfigRevision{}).Select("COALESCE(MAX(revision), 0)").
+		Where("task_type = ?", taskType).Scan(&last).Error; err != nil {
+		return nil, err
+	}
+
+	// 引入修订之前已存在的配置，先记录一个基线版本
+	if last == 0 && oldConfig != nil {
+		baseline, err := newRevision(oldConfig, nil, 1, "", RevisionActionBaseline)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Create(baseline).Error; err != nil {
+			return nil, err
+		}
+		last = 1
+	}
+
+	revision, err := newRevision(newConfig, oldConfig, last+1, author, action)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return nil, err
+	}
+	if action != RevisionActionDelete {
+		err = tx.Model(&TaskConfig{}).Where("task_type = ?", taskType).Update("revision", revision.Revision).Error
+		if err != nil {
+			return nil, err
+		}
+		newConfig.Revision = revision.Revision
+	}
+	return revision, nil
+}
+
+func newRevision(taskConfig, previous *TaskConfig, number int, author string, action string) (*TaskConfigRevision, error) {
+	snapshot := *taskConfig
+	snapshot.Revision = number
+	config, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := json.Marshal(DiffTaskConfig(previous, taskConfig))
+	if err != nil {
+		return nil, err
+	}
+	return &TaskConfigRevision{
+		TaskType:   taskConfig.TaskType,
+		Revision:   number,
+		Action:     action,
+		Author:     author,
+		Config:     string(config),
+		Diff:       string(diff),
+		CreateTime: time.Now(),
+	}, nil
+}
+
+// ListTaskConfigRevisions 按修订号倒序列出任务类型的修订历史
+func ListTaskConfigRevisions(taskType string) ([]*TaskConfigRevision, error) {
+	var revisions []*TaskConfigRevision
+	if err := db.Where("task_type = ?", taskType).Order("revision DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetTaskConfigRevision 获取指定修订，并解析出当时的配置
+func GetTaskConfigRevision(taskType string, revision int) (*TaskConfigRevision, *TaskConfig, error) {
+	var record TaskConfigRevision
+	if err := db.Where("task_type = ? AND revision = ?", taskType, revision).First(&record).Error; err != nil {
+		return nil, nil, err
+	}
+	var taskConfig TaskConfig
+	if err := json.Unmarshal([]byte(record.Config), &taskConfig); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse revision %d: %w", revision, err)
+	}
+	return &record, &taskConfig, nil
+}
+
+// TaskDispatchConfig 返回下发任务使用的配置，即任务记录的修订对应的配置
+//
+// 修订不存在时使用当前配置。修订快照中早期保存的明文凭据已脱敏，这种情况下沿用当前配置的凭据。
+func TaskDispatchConfig(task *Task) *TaskConfig {
+	current := GetTaskConfig(task.TaskType)
+	if current == nil || task.ConfigRevision == 0 || task.ConfigRevision == current.Revision {
+		return current
+	}
+	_, snapshot, err := GetTaskConfigRevision(task.TaskType, task.ConfigRevision)
+	if err != nil {
+		return current
+	}
+	if snapshot.AuthPassword == RedactedValue {
+		snapshot.AuthPassword = current.AuthPassword
+	}
+	if snapshot.ClientKey == RedactedValue {
+		snapshot.ClientKey = current.ClientKey
+	}
+	return snapshot
+}
+
+// RollbackTaskConfig 将配置恢复为指定修订的内容，并记录为一次新的修订
+func RollbackTaskConfig(taskType string, revision int, author string) (*TaskConfig, error) {
+	_, target, err := GetTaskConfigRevision(taskType, revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveTaskConfig(taskType, target, author, RevisionActionRollback); err != nil {
+		return nil, err
+	}
+	return GetTaskConfig(taskType), nil
+}
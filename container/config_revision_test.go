@@ -0,0 +1,55 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTaskConfig(t *testing.T) {
+	enabled, disabled := true, false
+	base := TaskConfig{TaskType: "a", Title: "A", RunEndpoint: "http://localhost/run"}
+	tests := []struct {
+		name   string
+		old    *TaskConfig
+		new    func(TaskConfig) *TaskConfig
+		fields []string
+	}{
+		{"unchanged", &base, func(c TaskConfig) *TaskConfig { return &c }, nil},
+		{"title", &base, func(c TaskConfig) *TaskConfig { c.Title = "B"; return &c }, []string{"title"}},
+		{"revision and managed ignored", &base, func(c TaskConfig) *TaskConfig {
+			c.Revision, c.Managed, c.ManagedSource = 3, true, "a.yaml"
+			return &c
+		}, nil},
+		{"nil enabled equals true", &base, func(c TaskConfig) *TaskConfig { c.Enabled = &enabled; return &c }, nil},
+		{"disabled", &base, func(c TaskConfig) *TaskConfig { c.Enabled = &disabled; return &c }, []string{"enabled"}},
+		{"empty tags equal nil", &base, func(c TaskConfig) *TaskConfig { c.Tags = []string{}; return &c }, nil},
+		{"empty headers equal nil", &base, func(c TaskConfig) *TaskConfig { c.DispatchHeaders = map[string]string{}; return &c }, nil},
+		{"sorted fields", &base, func(c TaskConfig) *TaskConfig {
+			c.Title, c.Category, c.Tags = "B", "ops", []string{"x"}
+			return &c
+		}, []string{"category", "tags", "title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, change := range DiffTaskConfig(tt.old, tt.new(*tt.old)) {
+				fields = append(fields, change.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("changed fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestDiffTaskConfigCreate(t *testing.T) {
+	changes := DiffTaskConfig(nil, &TaskConfig{TaskType: "a", Title: "A"})
+	for _, change := range changes {
+		if change.Old != nil {
+			t.Errorf("field %s old = %v, want nil", change.Field, change.Old)
+		}
+	}
+	if len(changes) == 0 {
+		t.Error("expected changes for a new config")
+	}
+}
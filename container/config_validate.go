@@ -0,0 +1,65 @@
+package container
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// ValidateTaskConfig 校验配置：task_type 不能为空，form 必须是合法的 JSON schema 且 pattern 可以编译，run_endpoint 必须是 http(s) 地址
+func ValidateTaskConfig(taskConfig *TaskConfig) error {
+	var errs []FieldError
+	if strings.TrimSpace(taskConfig.TaskType) == "" {
+		errs = append(errs, FieldError{Field: "task_type", Message: "必填"})
+	}
+	if strings.TrimSpace(taskConfig.Form) != "" {
+		if schema, err := ParseFormSchema(taskConfig.Form); err != nil {
+			errs = append(errs, FieldError{Field: "form", Message: err.Error()})
+		} else {
+			schema.patternErrors("", &errs)
+		}
+	}
+	if err := validateEndpoint(taskConfig.RunEndpoint); err != "" {
+		errs = append(errs, FieldError{Field: "run_endpoint", Message: err})
+	}
+	errs = append(errs, validateDispatchSettings(taskConfig)...)
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+func validateEndpoint(endpoint string) string {
+	if endpoint == "" {
+		return "必填"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "不是有效的 http(s) 地址"
+	}
+	return ""
+}
+
+var (
+	ErrTaskConfigNotFound = errors.New("任务配置不存在")
+	ErrTaskConfigExists   = errors.New("任务配置已存在")
+)
+
+// migrateTaskConfigKey 清理重复的 task_type 并建立唯一索引
+//
+// 早期的 task_config 表没有主键，同一 task_type 可能有多行，保留最后写入的一行。
+// 新建的表以 task_type 为主键，唯一索引对其是冗余但无害的。
+func migrateTaskConfigKey() error {
+	if !db.Migrator().HasTable(&TaskConfig{}) {
+		return nil
+	}
+	result := db.Exec("DELETE FROM task_config WHERE rowid NOT IN (SELECT MAX(rowid) FROM task_config GROUP BY task_type)")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("removed %d duplicate task_config rows", result.RowsAffected)
+	}
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_task_config_task_type ON task_config(task_type)").Error
+}
@@ -0,0 +1,125 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 总数的统计方式
+const (
+	TotalModeNone   = "none"
+	TotalModeExact  = "exact"
+	TotalModeApprox = "approx"
+)
+
+// approxTotalLimit 近似总数最多数到的行数，超过时只返回该值并标记为近似
+const approxTotalLimit = 10000
+
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// pageCursor 游标的内容，对调用方不透明
+type pageCursor struct {
+	CreateTime *time.Time `json:"t,omitempty"`
+	ID         uint       `json:"id"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// TaskPage 游标分页的一页任务，NextCursor 为空表示没有更多数据
+type TaskPage struct {
+	Tasks       []*Task `json:"tasks"`
+	NextCursor  string  `json:"next_cursor"`
+	PageSize    int     `json:"page_size"`
+	Total       *int64  `json:"total,omitempty"`
+	TotalApprox bool    `json:"total_approximate,omitempty"` // 总数达到统计上限，实际数量更多
+}
+
+// ListTasksByCursor 按 (create_time, id) 游标分页查询任务，新任务的创建不会使已翻过的页发生偏移
+//
+// cursor 为空时从第一页开始；totalMode 为 exact 时统计总数，approx 时最多数到 approxTotalLimit，其余情况不统计。
+func ListTasksByCursor(filter TaskFilter, cursor string, pageSize int, totalMode string) (*TaskPage, error) {
+	if filter.Sort != "" && filter.Sort != TaskSortCreateTime {
+		return nil, &ValidationError{Fields: []FieldError{{Field: "sort", Message: "游标分页只支持按创建时间排序"}}}
+	}
+	query, err := filter.apply(db.Model(&Task{}))
+	if err != nil {
+		return nil, err
+	}
+	order, _ := filter.order()
+
+	page := &TaskPage{Tasks: []*Task{}, PageSize: pageSize}
+	switch totalMode {
+	case TotalModeExact:
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	case TotalModeApprox:
+		var total int64
+		limited := query.Session(&gorm.Session{}).Select("task.id").Limit(approxTotalLimit)
+		if err := db.Table("(?) AS limited", limited).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total, page.TotalApprox = &total, total >= approxTotalLimit
+	}
+
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil || position.CreateTime == nil {
+			return nil, ErrInvalidCursor
+		}
+		compare := "<"
+		if !filter.Desc {
+			compare = ">"
+		}
+		query = query.Where("task.create_time "+compare+" ? OR (task.create_time = ? AND task.id "+compare+" ?)",
+			*position.CreateTime, *position.CreateTime, position.ID)
+	}
+	// 多取一行判断是否还有下一页
+	if err := query.Order(order).Limit(pageSize + 1).Find(&page.Tasks).Error; err != nil {
+		return nil, err
+	}
+	if len(page.Tasks) > pageSize {
+		page.Tasks = page.Tasks[:pageSize]
+		last := page.Tasks[pageSize-1]
+		page.NextCursor = encodeCursor(pageCursor{CreateTime: &last.CreateTime, ID: last.ID})
+	}
+	return page, nil
+}
+
+// TaskOutputCursor 返回从指定输出之后继续读取的游标
+func TaskOutputCursor(lastID int) string {
+	return encodeCursor(pageCursor{ID: uint(lastID)})
+}
+
+// ParseTaskOutputCursor 解析输出游标，返回游标位置的输出 ID
+func ParseTaskOutputCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	position, err := decodeCursor(cursor)
+	if err != nil || position.CreateTime != nil {
+		return 0, ErrInvalidCursor
+	}
+	return int(position.ID), nil
+}
@@ -0,0 +1,41 @@
+package container
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorCodec(t *testing.T) {
+	createTime := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		name   string
+		cursor pageCursor
+	}{
+		{"id only", pageCursor{ID: 42}},
+		{"create time", pageCursor{CreateTime: &createTime, ID: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeCursor(encodeCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.ID != tt.cursor.ID {
+				t.Errorf("id = %d, want %d", decoded.ID, tt.cursor.ID)
+			}
+			if (decoded.CreateTime == nil) != (tt.cursor.CreateTime == nil) ||
+				(decoded.CreateTime != nil && !decoded.CreateTime.Equal(*tt.cursor.CreateTime)) {
+				t.Errorf("create time = %v, want %v", decoded.CreateTime, tt.cursor.CreateTime)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, value := range []string{"@@", "bm90IGpzb24", "e30="} {
+		if _, err := decodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) err = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
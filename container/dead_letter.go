@@ -0,0 +1,237 @@
+package container
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 死信批量操作
+const (
+	DeadLetterActionRerun       = "rerun"
+	DeadLetterActionDelete      = "delete"
+	DeadLetterActionAcknowledge = "acknowledge"
+)
+
+const (
+	// 单次批量操作最多处理的任务数
+	maxDeadLetterBatch = 1000
+	// 每个死信分组返回的示例任务数
+	deadLetterSampleSize = 20
+)
+
+// deadLetterStatuses 下发或执行失败的任务状态
+var deadLetterStatuses = []string{TaskStatusError, TaskStatusFailed}
+
+// DeadLetterFilter 死信筛选条件，空字段不过滤
+type DeadLetterFilter struct {
+	TaskType            string     `json:"task_type"`
+	Message             *string    `json:"message"` // 精确匹配，空字符串匹配没有错误信息的任务
+	Statuses            []string   `json:"status"`
+	StartTime           *time.Time `json:"start_time"`
+	EndTime             *time.Time `json:"end_time"`
+	TaskIDs             []uint     `json:"task_ids"`
+	IncludeAcknowledged bool       `json:"include_acknowledged"`
+}
+
+// DeadLetterGroup 按任务类型与错误信息分组的死信
+type DeadLetterGroup struct {
+	TaskType  string    `json:"task_type"`
+	Message   string    `json:"message"`
+	Count     int64     `json:"count"`
+	FirstTime time.Time `json:"first_time"`
+	LastTime  time.Time `json:"last_time"`
+	TaskIDs   []uint    `json:"task_ids"` // 最近的若干个任务
+}
+
+// DeadLetterActionResult 批量操作的结果，dry run 时只有匹配数与任务ID
+type DeadLetterActionResult struct {
+	Action     string          `json:"action"`
+	DryRun     bool            `json:"dry_run"`
+	Matched    int64           `json:"matched"`
+	Limited    bool            `json:"limited"` // 匹配数超过单次上限，只处理了前一部分
+	TaskIDs    []uint          `json:"task_ids"`
+	NewTaskIDs map[uint]uint   `json:"new_task_ids,omitempty"` // 重跑时原任务ID到新任务ID
+	Errors     map[uint]string `json:"errors,omitempty"`
+}
+
+func (f *DeadLetterFilter) apply(query *gorm.DB) (*gorm.DB, error) {
+	statuses := deadLetterStatuses
+	if len(f.Statuses) > 0 {
+		for _, status := range f.Statuses {
+			if !slices.Contains(deadLetterStatuses, status) {
+				return nil, &ValidationError{Fields: []FieldError{{Field: "status", Message: fmt.Sprintf("无效的死信状态：%s", status)}}}
+			}
+		}
+		statuses = f.Statuses
+	}
+	query = query.Where("status IN ?", statuses)
+	if !f.IncludeAcknowledged {
+		query = query.Where("acknowledged_at IS NULL")
+	}
+	if f.TaskType != "" {
+		query = query.Where("task_type = ?", f.TaskType)
+	}
+	if f.Message != nil {
+		query = query.Where("message = ?", *f.Message)
+	}
+	if f.StartTime != nil {
+		query = query.Where("create_time >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		query = query.Where("create_time < ?", *f.EndTime)
+	}
+	if len(f.TaskIDs) > 0 {
+		query = query.Where("id IN ?", f.TaskIDs)
+	}
+	return query, nil
+}
+
+// ListDeadLetters 按任务类型与错误信息分组列出死信，数量多的分组在前
+func ListDeadLetters(filter DeadLetterFilter) ([]*DeadLetterGroup, error) {
+	query, err := filter.apply(db.Model(&Task{}))
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskType  string
+		Message   string
+		Count     int64
+		FirstTime string
+		LastTime  string
+	}
+	err = query.Session(&gorm.Session{}).
+		Select("task_type, message, COUNT(*) AS count, MIN(create_time) AS first_time, MAX(create_time) AS last_time").
+		Group("task_type, message").
+		Order("count DESC, last_time DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*DeadLetterGroup, 0, len(rows))
+	for _, row := range rows {
+		group := &DeadLetterGroup{
+			TaskType:  row.TaskType,
+			Message:   row.Message,
+			Count:     row.Count,
+			FirstTime: parseDBTime(row.FirstTime),
+			LastTime:  parseDBTime(row.LastTime),
+		}
+		err := query.Session(&gorm.Session{}).
+			Where("task_type = ? AND message = ?", row.TaskType, row.Message).
+			Order("id DESC").
+			Limit(deadLetterSampleSize).
+			Pluck("id", &group.TaskIDs).Error
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parseDBTime 解析聚合查询返回的时间文本，聚合结果不会经过字段类型转换
+func parseDBTime(value string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ApplyDeadLetterAction 对匹配的死信执行批量操作，dryRun 为 true 时只返回匹配的任务
+//
+// 重跑会创建新任务并在后台下发，原任务随之被确认。
+func ApplyDeadLetterAction(filter DeadLetterFilter, action string, dryRun bool, operator string) (*DeadLetterActionResult, error) {
+	switch action {
+	case DeadLetterActionRerun, DeadLetterActionDelete, DeadLetterActionAcknowledge:
+	default:
+		return nil, &ValidationError{Fields: []FieldError{{Field: "action", Message: fmt.Sprintf("不支持的操作：%s", action)}}}
+	}
+	query, err := filter.apply(db.Model(&Task{}))
+	if err != nil {
+		return nil, err
+	}
+	result := &DeadLetterActionResult{Action: action, DryRun: dryRun, TaskIDs: []uint{}}
+	if err := query.Session(&gorm.Session{}).Count(&result.Matched).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Session(&gorm.Session{}).Order("id ASC").Limit(maxDeadLetterBatch).Pluck("id", &result.TaskIDs).Error; err != nil {
+		return nil, err
+	}
+	result.Limited = result.Matched > int64(len(result.TaskIDs))
+	if dryRun || len(result.TaskIDs) == 0 {
+		return result, nil
+	}
+
+	result.Errors = map[uint]string{}
+	switch action {
+	case DeadLetterActionAcknowledge:
+		if err := acknowledgeTasks(result.TaskIDs, operator); err != nil {
+			return nil, err
+		}
+	case DeadLetterActionDelete:
+		for _, taskID := range result.TaskIDs {
+			if err := DeleteTask(taskID); err != nil {
+				result.Errors[taskID] = err.Error()
+			}
+		}
+	case DeadLetterActionRerun:
+		result.NewTaskIDs = map[uint]uint{}
+		var rerun, dispatch []uint
+		for _, taskID := range result.TaskIDs {
+			task := GetTask(taskID)
+			if task == nil {
+				continue
+			}
+			status, err := CheckTaskTypeEnabled(GetTaskConfig(task.TaskType))
+			if err != nil {
+				result.Errors[taskID] = err.Error()
+				continue
+			}
+			newTask, err := RerunTask(taskID, status, operator)
+			if err != nil {
+				result.Errors[taskID] = err.Error()
+				continue
+			}
+			result.NewTaskIDs[taskID] = newTask.ID
+			rerun = append(rerun, taskID)
+			if status == TaskStatusReady {
+				dispatch = append(dispatch, newTask.ID)
+			}
+		}
+		if err := acknowledgeTasks(rerun, operator); err != nil {
+			return nil, err
+		}
+		go dispatchTasks(dispatch)
+	}
+	return result, nil
+}
+
+func acknowledgeTasks(taskIDs []uint, operator string) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	return db.Model(&Task{}).Where("id IN ?", taskIDs).Updates(map[string]interface{}{
+		"acknowledged_at": time.Now(),
+		"acknowledged_by": operator,
+	}).Error
+}
+
+// dispatchTasks 依次下发任务，失败的任务由下发函数标记为 error
+func dispatchTasks(taskIDs []uint) {
+	if taskDispatcher == nil {
+		log.Printf("dispatch %d tasks failed: %v", len(taskIDs), errNoTaskDispatcher)
+		return
+	}
+	for _, taskID := range taskIDs {
+		if err := taskDispatcher(taskID); err != nil {
+			log.Printf("dispatch task %d failed: %v", taskID, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package container
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+// TaskTypeDisabledError 任务类型已停用
+type TaskTypeDisabledError struct {
+	TaskType string
+	Message  string
+}
+
+func (e *TaskTypeDisabledError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "任务类型 " + e.TaskType + " 已停用"
+}
+
+// CheckTaskTypeEnabled 检查任务类型是否可以创建任务
+//
+// 返回的状态为新任务的初始状态：启用时为 ready，停用但允许排队时为 queued；停用且不允许排队时返回 TaskTypeDisabledError。
+func CheckTaskTypeEnabled(taskConfig *TaskConfig) (string, error) {
+	if taskConfig == nil || taskConfig.IsEnabled() {
+		return TaskStatusReady, nil
+	}
+	if taskConfig.QueueWhenDisabled {
+		return TaskStatusQueued, nil
+	}
+	return "", &TaskTypeDisabledError{TaskType: taskConfig.TaskType, Message: taskConfig.MaintenanceMessage}
+}
+
+var (
+	taskDispatcher      func(taskID uint) error
+	dispatchQueuedMu    sync.Mutex
+	errNoTaskDispatcher = errors.New("task dispatcher is not registered")
+)
+
+// RegisterTaskDispatcher 注册任务下发函数，排队的任务在任务类型重新启用后通过它下发
+func RegisterTaskDispatcher(dispatcher func(taskID uint) error) {
+	taskDispatcher = dispatcher
+}
+
+// DispatchQueuedTasks 下发已启用任务类型中排队的任务，taskType 为空时处理所有任务类型
+func DispatchQueuedTasks(taskType string) error {
+	if taskDispatcher == nil {
+		return errNoTaskDispatcher
+	}
+	dispatchQueuedMu.Lock()
+	defer dispatchQueuedMu.Unlock()
+
+	query := db.Where("status = ?", TaskStatusQueued)
+	if taskType != "" {
+		query = query.Where("task_type = ?", taskType)
+	}
+	var tasks []*Task
+	if err := query.Order("id asc").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	enabled := map[string]bool{}
+	for _, task := range tasks {
+		isEnabled, ok := enabled[task.TaskType]
+		if !ok {
+			taskConfig := GetTaskConfig(task.TaskType)
+			isEnabled = taskConfig == nil || taskConfig.IsEnabled()
+			enabled[task.TaskType] = isEnabled
+		}
+		if !isEnabled {
+			continue
+		}
+		// 只有仍在排队的任务才会被下发，避免重复下发
+		result := db.Model(&Task{}).Where("id = ? AND status = ?", task.ID, TaskStatusQueued).Update("status", TaskStatusReady)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := taskDispatcher(task.ID); err != nil {
+			log.Printf("dispatch queued task %d failed: %v", task.ID, err)
+		}
+	}
+	return nil
+}
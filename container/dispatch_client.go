@@ -0,0 +1,212 @@
+package container
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 下发请求的认证方式
+const (
+	AuthTypeBasic  = "basic"
+	AuthTypeBearer = "bearer"
+)
+
+var (
+	// dispatchTransport 没有 TLS 与代理设置的任务类型共用的连接池
+	dispatchTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+	// dispatchTransports 按 TLS 与代理设置缓存的连接池，相同设置的任务类型共用
+	dispatchTransports   = map[string]*http.Transport{}
+	dispatchTransportsMu sync.Mutex
+)
+
+// resolveConfigSecrets 将配置值中的密钥引用替换为明文
+func resolveConfigSecrets(taskType string, values ...string) ([]string, error) {
+	resolved := make([]string, len(values))
+	var secrets map[string]string
+	for i, value := range values {
+		if secrets == nil && secretRefPattern.MatchString(value) {
+			var err error
+			if secrets, err = ResolveTaskSecrets(taskType); err != nil {
+				return nil, err
+			}
+		}
+		resolved[i] = value
+		if secrets != nil {
+			resolved[i] = ReplaceSecretRefs(value, secrets)
+		}
+		if ref := secretRefPattern.FindStringSubmatch(resolved[i]); ref != nil {
+			return nil, fmt.Errorf("密钥 %s 不存在", ref[1])
+		}
+	}
+	return resolved, nil
+}
+
+// DispatchClient 返回下发任务使用的 HTTP 客户端，timeout 为 0 时使用全局配置
+func DispatchClient(taskConfig *TaskConfig) (*http.Client, error) {
+	timeout := GetConfig().DispatchTimeout
+	if taskConfig == nil {
+		return &http.Client{Transport: dispatchTransport, Timeout: timeout}, nil
+	}
+	if taskConfig.DispatchTimeout > 0 {
+		timeout = time.Duration(taskConfig.DispatchTimeout) * time.Second
+	}
+	transport, err := dispatchTransportFor(taskConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+func dispatchTransportFor(taskConfig *TaskConfig) (*http.Transport, error) {
+	if taskConfig.CACert == "" && taskConfig.ClientCert == "" && taskConfig.ProxyURL == "" {
+		return dispatchTransport, nil
+	}
+	values, err := resolveConfigSecrets(taskConfig.TaskType, taskConfig.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	clientKey := values[0]
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{taskConfig.CACert, taskConfig.ClientCert, clientKey, taskConfig.ProxyURL}, "\x00")))
+	key := hex.EncodeToString(sum[:])
+	dispatchTransportsMu.Lock()
+	defer dispatchTransportsMu.Unlock()
+	if transport, ok := dispatchTransports[key]; ok {
+		return transport, nil
+	}
+
+	transport := dispatchTransport.Clone()
+	tlsConfig := &tls.Config{}
+	if taskConfig.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(taskConfig.CACert)) {
+			return nil, errors.New("CA 证书不是有效的 PEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if taskConfig.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(taskConfig.ClientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("客户端证书无效：%w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	if taskConfig.ProxyURL != "" {
+		proxy, err := url.Parse(taskConfig.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址无效：%w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	dispatchTransports[key] = transport
+	return transport, nil
+}
+
+// HasDispatchCredentials 配置是否设置了认证、客户端证书或引用密钥的请求头
+func (c *TaskConfig) HasDispatchCredentials() bool {
+	if c.AuthType != "" || c.AuthPassword != "" || c.ClientCert != "" || c.ClientKey != "" {
+		return true
+	}
+	for _, value := range c.DispatchHeaders {
+		if secretRefPattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutDispatchCredentials 返回去掉请求头、认证与客户端证书的副本，下发到配置之外的执行地址时使用
+func (c *TaskConfig) WithoutDispatchCredentials() *TaskConfig {
+	if c == nil {
+		return nil
+	}
+	stripped := *c
+	stripped.DispatchHeaders = nil
+	stripped.AuthType, stripped.AuthUsername, stripped.AuthPassword = "", "", ""
+	stripped.ClientCert, stripped.ClientKey = "", ""
+	return &stripped
+}
+
+// ApplyDispatchAuth 为下发请求设置配置中的请求头与认证信息
+func ApplyDispatchAuth(request *http.Request, taskConfig *TaskConfig) error {
+	if taskConfig == nil {
+		return nil
+	}
+	names := make([]string, 0, len(taskConfig.DispatchHeaders))
+	values := make([]string, 0, len(taskConfig.DispatchHeaders)+1)
+	for name, value := range taskConfig.DispatchHeaders {
+		names = append(names, name)
+		values = append(values, value)
+	}
+	values = append(values, taskConfig.AuthPassword)
+	resolved, err := resolveConfigSecrets(taskConfig.TaskType, values...)
+	if err != nil {
+		return err
+	}
+	for i, name := range names {
+		request.Header.Set(name, resolved[i])
+	}
+	password := resolved[len(resolved)-1]
+	switch taskConfig.AuthType {
+	case AuthTypeBasic:
+		request.SetBasicAuth(taskConfig.AuthUsername, password)
+	case AuthTypeBearer:
+		request.Header.Set("Authorization", "Bearer "+password)
+	}
+	return nil
+}
+
+// validateDispatchSettings 校验下发设置，密钥引用在下发时才解析，证书与私钥是否匹配也在下发时检查
+func validateDispatchSettings(taskConfig *TaskConfig) []FieldError {
+	var errs []FieldError
+	if taskConfig.DispatchTimeout < 0 {
+		errs = append(errs, FieldError{Field: "dispatch_timeout", Message: "不能小于 0"})
+	}
+	switch taskConfig.AuthType {
+	case "", AuthTypeBasic, AuthTypeBearer:
+	default:
+		errs = append(errs, FieldError{Field: "auth_type", Message: "只支持 basic 或 bearer"})
+	}
+	if taskConfig.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(taskConfig.CACert)) {
+		errs = append(errs, FieldError{Field: "ca_cert", Message: "不是有效的 PEM 证书"})
+	}
+	if taskConfig.AuthPassword != "" && !isSecretRef(taskConfig.AuthPassword) {
+		errs = append(errs, FieldError{Field: "auth_password", Message: "只能使用 ${secret:NAME} 引用密钥"})
+	}
+	if (taskConfig.ClientCert == "") != (taskConfig.ClientKey == "") {
+		errs = append(errs, FieldError{Field: "client_cert", Message: "客户端证书与私钥需要同时设置"})
+	} else if taskConfig.ClientCert != "" {
+		if block, _ := pem.Decode([]byte(taskConfig.ClientCert)); block == nil || block.Type != "CERTIFICATE" {
+			errs = append(errs, FieldError{Field: "client_cert", Message: "不是有效的 PEM 证书"})
+		}
+		if !isSecretRef(taskConfig.ClientKey) {
+			errs = append(errs, FieldError{Field: "client_key", Message: "只能使用 ${secret:NAME} 引用密钥"})
+		}
+	}
+	if taskConfig.ProxyURL != "" {
+		if u, err := url.Parse(taskConfig.ProxyURL); err != nil || u.Host == "" {
+			errs = append(errs, FieldError{Field: "proxy_url", Message: "不是有效的代理地址"})
+		}
+	}
+	for name := range taskConfig.DispatchHeaders {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " :\r\n") {
+			errs = append(errs, FieldError{Field: "dispatch_headers", Message: "无效的请求头名称：" + name})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,119 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 任务状态
+const (
+	TaskStatusReady   = "ready"
+	TaskStatusQueued  = "queued" // 任务类型停用期间创建，等待重新启用后下发
+	TaskStatusRunning = "running"
+	TaskStatusSuccess = "success"
+	TaskStatusFailed  = "failed"
+	TaskStatusError   = "error"
+)
+
+// IsTerminalStatus 判断任务是否已经结束，queued、ready 与 running 之外的状态都视为结束
+func IsTerminalStatus(status string) bool {
+	return status != TaskStatusQueued && status != TaskStatusReady && status != TaskStatusRunning
+}
+
+// taskWatcher 一个任务的订阅通道及正在等待的请求数
+type taskWatcher struct {
+	ch      chan struct{}
+	waiters int
+}
+
+var (
+	taskWatchers   = map[uint]*taskWatcher{}
+	taskWatchersMu sync.Mutex
+)
+
+// WatchTask 返回一个在任务有新输出或状态变化时关闭的通道，等待结束后必须调用 release
+//
+// 最后一个等待者 release 时删除订阅，超时离开的请求不会让没有新输出的任务一直占用内存。
+func WatchTask(taskID uint) (changed <-chan struct{}, release func()) {
+	taskWatchersMu.Lock()
+	defer taskWatchersMu.Unlock()
+	w, ok := taskWatchers[taskID]
+	if !ok {
+		w = &taskWatcher{ch: make(chan struct{})}
+		taskWatchers[taskID] = w
+	}
+	w.waiters++
+	return w.ch, func() {
+		taskWatchersMu.Lock()
+		defer taskWatchersMu.Unlock()
+		w.waiters--
+		if w.waiters == 0 && taskWatchers[taskID] == w {
+			delete(taskWatchers, taskID)
+		}
+	}
+}
+
+// notifyTask 唤醒所有等待该任务的请求
+func notifyTask(taskID uint) {
+	taskWatchersMu.Lock()
+	defer taskWatchersMu.Unlock()
+	if w, ok := taskWatchers[taskID]; ok {
+		close(w.ch)
+		delete(taskWatchers, taskID)
+	}
+}
+
+// WaitTaskOutput 等待任务出现 ID 大于 lastID 的输出，直到超时或任务结束
+func WaitTaskOutput(ctx context.Context, taskID uint, lastID int, pageSize int, timeout time.Duration) ([]TaskOutput, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// 先订阅再查询，避免错过查询与等待之间的通知
+		changed, release := WatchTask(taskID)
+		outputs, err := GetTaskOutput(taskID, lastID, pageSize)
+		if err != nil || len(outputs) > 0 {
+			release()
+			return outputs, err
+		}
+		task := GetTask(taskID)
+		if task == nil || IsTerminalStatus(task.Status) {
+			release()
+			return outputs, nil
+		}
+		select {
+		case <-changed:
+			release()
+		case <-timer.C:
+			release()
+			return outputs, nil
+		case <-ctx.Done():
+			release()
+			return outputs, ctx.Err()
+		}
+	}
+}
+
+// WaitTask 等待任务进入结束状态，第二个返回值表示任务是否已经结束
+func WaitTask(ctx context.Context, taskID uint, timeout time.Duration) (*Task, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		changed, release := WatchTask(taskID)
+		task := GetTask(taskID)
+		if task == nil || IsTerminalStatus(task.Status) {
+			release()
+			return task, task != nil
+		}
+		select {
+		case <-changed:
+			release()
+		case <-timer.C:
+			release()
+			return task, false
+		case <-ctx.Done():
+			release()
+			return task, false
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package container
+
+import "testing"
+
+func TestWatchTaskRelease(t *testing.T) {
+	const taskID = 1 << 30
+	watching := func() bool {
+		taskWatchersMu.Lock()
+		defer taskWatchersMu.Unlock()
+		_, ok := taskWatchers[taskID]
+		return ok
+	}
+
+	_, releaseA := WatchTask(taskID)
+	_, releaseB := WatchTask(taskID)
+	releaseA()
+	if !watching() {
+		t.Fatal("watcher removed while another request is still waiting")
+	}
+	releaseB()
+	if watching() {
+		t.Fatal("watcher kept after the last waiter released it")
+	}
+
+	changed, release := WatchTask(taskID)
+	notifyTask(taskID)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("notifyTask did not close the channel")
+	}
+	next, releaseNext := WatchTask(taskID)
+	release()
+	if !watching() {
+		t.Fatal("releasing a notified watcher removed the new subscription")
+	}
+	releaseNext()
+	select {
+	case <-next:
+		t.Fatal("new subscription closed without a notification")
+	default:
+	}
+}
@@ -0,0 +1,292 @@
+package container
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaskOutputArchive 已压缩归档的任务输出，对应 task_output_archive 表
+type TaskOutputArchive struct {
+	ID         uint      `gorm:"autoIncrement;column:id" json:"id"`
+	TaskID     uint      `gorm:"column:task_id;index:idx_task_output_archive_range,priority:1" json:"task_id"`
+	FirstID    uint      `gorm:"column:first_id" json:"first_id"`
+	LastID     uint      `gorm:"column:last_id;index:idx_task_output_archive_range,priority:2" json:"last_id"`
+	Lines      int       `gorm:"column:lines" json:"lines"`
+	Path       string    `gorm:"column:path" json:"path"`
+	CreateTime time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+func (TaskOutputArchive) TableName() string {
+	return "task_output_archive"
+}
+
+// outputRetention 生效的保留策略
+type outputRetention struct {
+	MaxAge   time.Duration
+	MaxLines int
+	Archive  bool
+}
+
+// getOutputRetention 合并全局配置与任务类型配置
+func getOutputRetention(taskConfig *TaskConfig) outputRetention {
+	cfg := GetConfig()
+	retention := outputRetention{
+		MaxAge:   cfg.OutputMaxAge,
+		MaxLines: cfg.OutputMaxLines,
+		Archive:  cfg.OutputArchive,
+	}
+	if taskConfig == nil {
+		return retention
+	}
+	if taskConfig.RetentionMaxAge > 0 {
+		retention.MaxAge = time.Duration(taskConfig.RetentionMaxAge) * time.Second
+	}
+	if taskConfig.RetentionMaxLines > 0 {
+		retention.MaxLines = taskConfig.RetentionMaxLines
+	}
+	if taskConfig.RetentionArchive != nil {
+		retention.Archive = *taskConfig.RetentionArchive
+	}
+	return retention
+}
+
+// StartOutputCompaction 启动后台任务，定期按保留策略归档并清理任务输出
+func StartOutputCompaction() {
+	interval := GetConfig().OutputCompactInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := CompactTaskOutput(); err != nil {
+				log.Printf("compact task output failed: %v", err)
+			}
+		}
+	}()
+}
+
+// CompactTaskOutput 对所有有输出的任务执行一次保留策略，并清理已删除任务遗留的归档
+func CompactTaskOutput() error {
+	var orphans []uint
+	err := db.Model(&TaskOutputArchive{}).Distinct("task_id").
+		Where("task_id NOT IN (SELECT id FROM task)").Pluck("task_id", &orphans).Error
+	if err != nil {
+		return err
+	}
+	for _, taskID := range orphans {
+		if err := deleteTaskOutputArchives(taskID); err != nil {
+			log.Printf("delete output archives of task %d failed: %v", taskID, err)
+		}
+	}
+
+	var rows []struct {
+		TaskID   uint
+		TaskType string
+	}
+	err = db.Table("task_output").
+		Select("DISTINCT task_output.task_id AS task_id, task.task_type AS task_type").
+		Joins("LEFT JOIN task ON task.id = task_output.task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	configs := map[string]*TaskConfig{}
+	for _, row := range rows {
+		taskConfig, ok := configs[row.TaskType]
+		if !ok {
+			taskConfig = GetTaskConfig(row.TaskType)
+			configs[row.TaskType] = taskConfig
+		}
+		if err := compactTaskOutput(row.TaskID, getOutputRetention(taskConfig)); err != nil {
+			log.Printf("compact output of task %d failed: %v", row.TaskID, err)
+		}
+	}
+	return nil
+}
+
+// compactTaskOutput 计算需要清理的最大输出 ID，并归档、删除该 ID 及之前的输出
+func compactTaskOutput(taskID uint, retention outputRetention) error {
+	var cutoffID uint
+	if retention.MaxAge > 0 {
+		var id uint
+		err := db.Model(&TaskOutput{}).Select("COALESCE(MAX(id), 0)").
+			Where("task_id = ? AND create_time < ?", taskID, time.Now().Add(-retention.MaxAge)).
+			Scan(&id).Error
+		if err != nil {
+			return err
+		}
+		cutoffID = max(cutoffID, id)
+	}
+	if retention.MaxLines > 0 {
+		var ids []uint
+		err := db.Model(&TaskOutput{}).Where("task_id = ?", taskID).
+			Order("id desc").Offset(retention.MaxLines).Limit(1).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			cutoffID = max(cutoffID, ids[0])
+		}
+	}
+	if cutoffID == 0 {
+		return nil
+	}
+
+	if !retention.Archive {
+		return db.Where("task_id = ? AND id <= ?", taskID, cutoffID).Delete(&TaskOutput{}).Error
+	}
+
+	var outputs []TaskOutput
+	if err := db.Where("task_id = ? AND id <= ?", taskID, cutoffID).Order("id asc").Find(&outputs).Error; err != nil {
+		return err
+	}
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	archive := TaskOutputArchive{
+		TaskID:     taskID,
+		FirstID:    outputs[0].ID,
+		LastID:     outputs[len(outputs)-1].ID,
+		Lines:      len(outputs),
+		CreateTime: time.Now(),
+	}
+	archive.Path = filepath.Join(taskArchiveDir(taskID), fmt.Sprintf("%d-%d.jsonl.gz", archive.FirstID, archive.LastID))
+	if err := writeOutputArchive(archive.Path, outputs); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("task_id = ? AND id >= ? AND id <= ?", taskID, archive.FirstID, archive.LastID).
+			Delete(&TaskOutput{}).Error
+	})
+	if err != nil {
+		os.Remove(archive.Path)
+		return err
+	}
+	return nil
+}
+
+// taskArchiveDir 任务归档文件所在的目录
+func taskArchiveDir(taskID uint) string {
+	return filepath.Join(GetConfig().TempDir, "task_output", fmt.Sprintf("%d", taskID))
+}
+
+// writeOutputArchive 将输出逐行写为 gzip 压缩的 JSON Lines 文件
+func writeOutputArchive(path string, outputs []TaskOutput) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for _, output := range outputs {
+		if err := encoder.Encode(output); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// scanOutputArchive 逐行解压读取归档文件，fn 返回 false 时停止读取
+func scanOutputArchive(path string, fn func(TaskOutput) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	for decoder.More() {
+		var output TaskOutput
+		if err := decoder.Decode(&output); err != nil {
+			return err
+		}
+		if !fn(output) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readArchivedOutput 从归档中读取 ID 大于 lastID 的输出，最多 pageSize 行
+//
+// 按 (task_id, last_id) 索引逐个查找覆盖 lastID 之后输出的归档文件，读满一页后停止解压，不再打开后面的文件。
+func readArchivedOutput(taskID uint, lastID int, pageSize int) ([]TaskOutput, error) {
+	var result []TaskOutput
+	full := func() bool { return pageSize > 0 && len(result) >= pageSize }
+	for !full() {
+		var archive TaskOutputArchive
+		err := db.Where("task_id = ? AND last_id > ?", taskID, lastID).Order("last_id asc").Limit(1).Find(&archive).Error
+		if err != nil {
+			return nil, err
+		}
+		if archive.ID == 0 {
+			break
+		}
+		err = scanOutputArchive(archive.Path, func(output TaskOutput) bool {
+			if int(output.ID) <= lastID {
+				return true
+			}
+			result = append(result, output)
+			return !full()
+		})
+		if err != nil {
+			// 归档文件丢失时跳过，不影响后续输出的读取
+			log.Printf("read output archive %s failed: %v", archive.Path, err)
+		}
+		if !full() {
+			lastID = int(archive.LastID)
+		}
+	}
+	return result, nil
+}
+
+// deleteTaskOutputArchives 删除任务的归档记录及整个归档目录，包括未登记的残留文件
+func deleteTaskOutputArchives(taskID uint) error {
+	var archives []TaskOutputArchive
+	if err := db.Where("task_id = ?", taskID).Find(&archives).Error; err != nil {
+		return err
+	}
+	for _, archive := range archives {
+		os.Remove(archive.Path)
+	}
+	if err := os.RemoveAll(taskArchiveDir(taskID)); err != nil {
+		return err
+	}
+	return db.Where("task_id = ?", taskID).Delete(&TaskOutputArchive{}).Error
+}
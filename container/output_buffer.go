@@ -0,0 +1,206 @@
+package container
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// outputRing 单个任务的输出缓冲
+//
+// rows 中保存该任务最近的输出行，前 flushed 行已经写入数据库，其余行等待批量落库。
+// 缓冲中包含该任务所有 ID 大于 coveredFrom 的输出行，因此 last_id >= coveredFrom
+// 的实时读取可以直接由内存提供。
+type outputRing struct {
+	mu          sync.Mutex
+	rows        []TaskOutput
+	flushed     int
+	coveredFrom uint
+	lastActive  time.Time
+	closed      bool
+}
+
+var (
+	outputSeq     atomic.Uint64
+	outputRings   = map[uint]*outputRing{}
+	outputRingsMu sync.Mutex
+	outputFlushCh = make(chan struct{}, 1)
+	outputFlushMu sync.Mutex
+)
+
+// initOutputBuffer 初始化输出 ID 序列并启动后台落库协程
+func initOutputBuffer() error {
+	var maxID uint64
+	if err := db.Model(&TaskOutput{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return err
+	}
+	outputSeq.Store(maxID)
+	go outputFlushLoop()
+	return nil
+}
+
+func outputFlushLoop() {
+	ticker := time.NewTicker(GetConfig().OutputFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-outputFlushCh:
+		}
+		if err := FlushTaskOutput(); err != nil {
+			log.Printf("flush task output failed: %v", err)
+		}
+	}
+}
+
+// getOutputRing 获取任务的输出缓冲，不存在时按需创建
+func getOutputRing(taskID uint, create bool) *outputRing {
+	outputRingsMu.Lock()
+	defer outputRingsMu.Unlock()
+	ring, ok := outputRings[taskID]
+	if !ok && create {
+		// 当前序列之前的输出都已经在数据库中
+		ring = &outputRing{coveredFrom: uint(outputSeq.Load())}
+		outputRings[taskID] = ring
+	}
+	return ring
+}
+
+// appendTaskOutput 写入一行输出到缓冲，达到行数阈值时通知后台落库
+func appendTaskOutput(taskID uint, output string) TaskOutput {
+	cfg := GetConfig()
+	ring := getOutputRing(taskID, true)
+
+	ring.mu.Lock()
+	// 缓冲已被回收时重新获取
+	for ring.closed {
+		ring.mu.Unlock()
+		ring = getOutputRing(taskID, true)
+		ring.mu.Lock()
+	}
+	row := TaskOutput{
+		ID:         uint(outputSeq.Add(1)),
+		TaskID:     taskID,
+		Output:     output,
+		CreateTime: time.Now(),
+	}
+	ring.rows = append(ring.rows, row)
+	ring.lastActive = row.CreateTime
+	// 超出容量时淘汰已落库的旧行
+	if over := len(ring.rows) - cfg.OutputBufferSize; over > 0 && ring.flushed > 0 {
+		over = min(over, ring.flushed)
+		ring.coveredFrom = ring.rows[over-1].ID
+		ring.rows = append([]TaskOutput(nil), ring.rows[over:]...)
+		ring.flushed -= over
+	}
+	pending := len(ring.rows) - ring.flushed
+	ring.mu.Unlock()
+	notifyTask(taskID)
+
+	if pending >= cfg.OutputFlushSize {
+		NotifyTaskOutputFlush()
+	}
+	return row
+}
+
+// readBufferedOutput 从缓冲中读取 ID 大于 lastID 的输出
+//
+// 第二个返回值表示缓冲是否覆盖了 lastID 之后的全部输出，为 false 时需要先查询数据库
+func readBufferedOutput(taskID uint, lastID int, pageSize int) ([]TaskOutput, bool) {
+	ring := getOutputRing(taskID, false)
+	if ring == nil {
+		return nil, false
+	}
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	var rows []TaskOutput
+	for _, row := range ring.rows {
+		if int(row.ID) <= lastID {
+			continue
+		}
+		if pageSize > 0 && len(rows) >= pageSize {
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows, lastID >= int(ring.coveredFrom)
+}
+
+// dropOutputRing 丢弃任务的输出缓冲，未落库的行不再写入
+func dropOutputRing(taskID uint) {
+	// 等待进行中的落库结束，避免已删除任务的输出被再次写入
+	outputFlushMu.Lock()
+	defer outputFlushMu.Unlock()
+	outputRingsMu.Lock()
+	delete(outputRings, taskID)
+	outputRingsMu.Unlock()
+}
+
+// FlushTaskOutput 将所有缓冲中未落库的输出批量写入数据库，并回收长时间空闲的缓冲、遮盖明文与开始时间标记
+func FlushTaskOutput() error {
+	outputFlushMu.Lock()
+	defer outputFlushMu.Unlock()
+
+	cfg := GetConfig()
+	idleBefore := time.Now().Add(-10 * cfg.OutputFlushInterval)
+	evictIdleOutputMasks(idleBefore)
+	evictIdleStartedTasks(idleBefore)
+
+	outputRingsMu.Lock()
+	rings := make(map[uint]*outputRing, len(outputRings))
+	for taskID, ring := range outputRings {
+		rings[taskID] = ring
+	}
+	outputRingsMu.Unlock()
+
+	var (
+		pending []TaskOutput
+		counts  = map[*outputRing]int{}
+	)
+	for taskID, ring := range rings {
+		ring.mu.Lock()
+		n := len(ring.rows) - ring.flushed
+		if n > 0 {
+			pending = append(pending, ring.rows[ring.flushed:]...)
+			counts[ring] = n
+		} else if ring.lastActive.Before(idleBefore) {
+			ring.closed = true
+			outputRingsMu.Lock()
+			if outputRings[taskID] == ring {
+				delete(outputRings, taskID)
+			}
+			outputRingsMu.Unlock()
+		}
+		ring.mu.Unlock()
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// 在同一个事务中写入，失败时整体保留在缓冲中等待下次重试
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(pending, cfg.OutputFlushSize).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for ring, n := range counts {
+		ring.mu.Lock()
+		ring.flushed += n
+		ring.mu.Unlock()
+	}
+	return nil
+}
+
+// NotifyTaskOutputFlush 请求后台尽快落库，用于任务结束等需要及时持久化的场景
+func NotifyTaskOutputFlush() {
+	select {
+	case outputFlushCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,155 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// TaskPreset 任务类型的预设输入，对应 task_preset 表
+//
+// 共享的预设所有人可见，私有的预设只有创建人可见。Stale 在读取时按当前表单重新校验得出。
+type TaskPreset struct {
+	ID         uint      `gorm:"autoIncrement;column:id" json:"id"`
+	TaskType   string    `gorm:"column:task_type;index" json:"task_type"`
+	Name       string    `gorm:"column:name" json:"name"`
+	Input      string    `gorm:"column:input" json:"input"`
+	Shared     bool      `gorm:"column:shared" json:"shared"`
+	Owner      string    `gorm:"column:owner" json:"owner"`
+	CreateTime time.Time `gorm:"column:create_time" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time" json:"update_time"`
+
+	Stale       bool         `gorm:"-" json:"stale"`
+	StaleFields []FieldError `gorm:"-" json:"stale_fields,omitempty"`
+}
+
+func (TaskPreset) TableName() string {
+	return "task_preset"
+}
+
+var (
+	ErrTaskPresetNotFound  = errors.New("预设不存在")
+	ErrTaskPresetForbidden = errors.New("只有预设的创建人可以修改或删除")
+	// ErrTaskPresetNoOperator 未识别操作人时不能创建、修改或删除预设
+	ErrTaskPresetNoOperator = errors.New("未识别操作人，请通过 Basic Auth 或 X-User 请求头提供")
+)
+
+// VisibleTo 判断预设对操作人是否可见，没有创建人的私有预设对任何人都不可见
+func (p *TaskPreset) VisibleTo(operator string) bool {
+	return p.Shared || (p.Owner != "" && p.Owner == operator)
+}
+
+// OwnedBy 判断操作人是否为预设的创建人，只有创建人可以修改或删除预设，没有创建人的预设不属于任何人
+func (p *TaskPreset) OwnedBy(operator string) bool {
+	return operator != "" && p.Owner == operator
+}
+
+// checkStale 按任务类型当前的表单校验预设输入
+func (p *TaskPreset) checkStale(form string) {
+	p.Stale, p.StaleFields = false, nil
+	if err := p.validate(form); err != nil {
+		p.Stale = true
+		if validationErr, ok := err.(*ValidationError); ok {
+			p.StaleFields = validationErr.Fields
+		}
+	}
+}
+
+// validate 预设可以包含模板，按渲染后的结果校验
+func (p *TaskPreset) validate(form string) error {
+	input, err := RenderInputTemplate(p.Input, TemplateContext{TaskType: p.TaskType, User: p.Owner})
+	if err != nil {
+		return err
+	}
+	return ValidateTaskInput(form, input)
+}
+
+func taskTypeForm(taskType string) string {
+	if taskConfig := GetTaskConfig(taskType); taskConfig != nil {
+		return taskConfig.Form
+	}
+	return ""
+}
+
+// ListTaskPresets 列出操作人可见的预设，taskType 为空时列出所有任务类型
+func ListTaskPresets(taskType string, operator string) ([]*TaskPreset, error) {
+	var presets []*TaskPreset
+	query := db.Where("shared = ? OR (owner <> '' AND owner = ?)", true, operator)
+	if taskType != "" {
+		query = query.Where("task_type = ?", taskType)
+	}
+	if err := query.Order("task_type ASC, name ASC, id ASC").Find(&presets).Error; err != nil {
+		return nil, err
+	}
+	forms := map[string]string{}
+	for _, preset := range presets {
+		form, ok := forms[preset.TaskType]
+		if !ok {
+			form = taskTypeForm(preset.TaskType)
+			forms[preset.TaskType] = form
+		}
+		preset.checkStale(form)
+	}
+	return presets, nil
+}
+
+// GetTaskPreset 获取预设，不存在或对操作人不可见时返回 ErrTaskPresetNotFound
+func GetTaskPreset(id uint, operator string) (*TaskPreset, error) {
+	var preset TaskPreset
+	if err := db.Where("id = ?", id).First(&preset).Error; err != nil || !preset.VisibleTo(operator) {
+		return nil, ErrTaskPresetNotFound
+	}
+	preset.checkStale(taskTypeForm(preset.TaskType))
+	return &preset, nil
+}
+
+// SaveTaskPreset 按当前表单校验后创建或更新预设，预设必须有创建人
+func SaveTaskPreset(preset *TaskPreset) error {
+	if preset.Owner == "" {
+		return ErrTaskPresetNoOperator
+	}
+	if err := preset.validate(taskTypeForm(preset.TaskType)); err != nil {
+		return err
+	}
+	now := time.Now()
+	preset.UpdateTime = now
+	if preset.ID == 0 {
+		preset.CreateTime = now
+		return db.Create(preset).Error
+	}
+	return db.Model(&TaskPreset{}).Where("id = ?", preset.ID).Updates(map[string]interface{}{
+		"name":        preset.Name,
+		"input":       preset.Input,
+		"shared":      preset.Shared,
+		"update_time": now,
+	}).Error
+}
+
+// DeleteTaskPreset 删除预设
+func DeleteTaskPreset(id uint) error {
+	return db.Delete(&TaskPreset{}, id).Error
+}
+
+// MergePresetInput 以预设输入为基础，用 overrides 中的字段覆盖，两者都必须是 JSON 对象
+func MergePresetInput(presetInput string, overrides string) (string, error) {
+	merged := map[string]interface{}{}
+	if presetInput != "" {
+		if err := json.Unmarshal([]byte(presetInput), &merged); err != nil {
+			return "", err
+		}
+	}
+	if overrides != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(overrides), &fields); err != nil {
+			return "", &ValidationError{Fields: []FieldError{{Field: "input", Message: "输入不是有效的 JSON 对象"}}}
+		}
+		for name, value := range fields {
+			merged[name] = value
+		}
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
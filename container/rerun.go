@@ -0,0 +1,98 @@
+package container
+
+import (
+	"errors"
+	"time"
+
+	"run-task/util"
+)
+
+// 任务与来源任务的关系
+const (
+	TaskOriginRerun = "rerun"
+	TaskOriginClone = "clone"
+)
+
+// 沿来源任务向上查找的最大层数，避免异常数据导致死循环
+const maxRerunChainDepth = 100
+
+var ErrTaskNotFound = errors.New("任务不存在")
+
+// TaskLink 重跑链上的任务摘要
+type TaskLink struct {
+	ID         uint      `json:"id"`
+	Origin     string    `json:"origin"`
+	Status     string    `json:"status"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+func newTaskLink(task *Task) TaskLink {
+	return TaskLink{ID: task.ID, Origin: task.Origin, Status: task.Status, CreateTime: task.CreateTime}
+}
+
+// RerunTask 以原任务的输入与执行地址创建新任务，status 为新任务的初始状态
+//
+// 输入中加密的敏感字段直接复制，不重新渲染模板也不重新校验。新任务记录原任务的配置修订，
+// 下发时使用该修订的配置，与原任务的执行条件一致。
+func RerunTask(taskID uint, status string, creator string) (*Task, error) {
+	original := GetTask(taskID)
+	if original == nil {
+		return nil, ErrTaskNotFound
+	}
+	task := &Task{
+		TaskType:        original.TaskType,
+		Input:           original.Input,
+		InputTemplate:   original.InputTemplate,
+		SensitiveFields: original.SensitiveFields,
+		RunEndpoint:     original.RunEndpoint,
+		ConfigRevision:  original.ConfigRevision,
+		CreateTime:      time.Now(),
+		Status:          status,
+		Creator:         creator,
+		ParentID:        original.ID,
+		Origin:          TaskOriginRerun,
+		Token:           util.RandomToken(16),
+	}
+	if err := insertTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// CloneTaskInput 返回原任务解密后的输入，overrides 中的字段覆盖原输入
+//
+// 原输入已经渲染过，其中的 {{ 被转义后原样保留，只有 overrides 中的模板会在创建时渲染。
+func CloneTaskInput(original *Task, overrides string) (string, error) {
+	input, err := decryptTaskInput(original.Input)
+	if err != nil {
+		return "", err
+	}
+	if input, err = EscapeInputTemplate(input); err != nil {
+		return "", err
+	}
+	return MergePresetInput(input, overrides)
+}
+
+// GetRerunChain 返回任务的来源链（从最早的任务开始）与直接由它重跑或复制出的任务
+func GetRerunChain(task *Task) ([]TaskLink, []TaskLink, error) {
+	ancestors := []TaskLink{}
+	parentID := task.ParentID
+	for depth := 0; parentID != 0 && depth < maxRerunChainDepth; depth++ {
+		parent := GetTask(parentID)
+		if parent == nil {
+			break
+		}
+		ancestors = append([]TaskLink{newTaskLink(parent)}, ancestors...)
+		parentID = parent.ParentID
+	}
+
+	var children []*Task
+	if err := db.Where("parent_id = ?", task.ID).Order("id ASC").Find(&children).Error; err != nil {
+		return nil, nil, err
+	}
+	descendants := make([]TaskLink, 0, len(children))
+	for _, child := range children {
+		descendants = append(descendants, newTaskLink(child))
+	}
+	return ancestors, descendants, nil
+}
@@ -0,0 +1,328 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormSchema form-render 使用的表单 schema，只解析服务端校验需要的字段
+type FormSchema struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Properties map[string]*FormSchema `json:"properties"`
+	Items      *FormSchema            `json:"items"`
+	Required   json.RawMessage        `json:"required"` // 字段上为 bool，对象上为字段名数组
+	Enum       []interface{}          `json:"enum"`
+	Min        *float64               `json:"min"`
+	Max        *float64               `json:"max"`
+	Minimum    *float64               `json:"minimum"`
+	Maximum    *float64               `json:"maximum"`
+	MinLength  *int                   `json:"minLength"`
+	MaxLength  *int                   `json:"maxLength"`
+	Pattern    string                 `json:"pattern"`
+	Rules      []FormRule             `json:"rules"`
+	Widget     string                 `json:"widget"`
+	Sensitive  bool                   `json:"sensitive"` // 敏感字段加密存储，接口中脱敏
+}
+
+// FormRule form-render 字段上的校验规则
+type FormRule struct {
+	Required bool     `json:"required"`
+	Pattern  string   `json:"pattern"`
+	Min      *float64 `json:"min"`
+	Max      *float64 `json:"max"`
+	Message  string   `json:"message"`
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 任务输入校验失败
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "校验失败：" + strings.Join(messages, "; ")
+}
+
+// ParseFormSchema 解析表单 schema，空字符串视为没有字段的表单
+func ParseFormSchema(form string) (*FormSchema, error) {
+	schema := &FormSchema{}
+	if strings.TrimSpace(form) == "" {
+		return schema, nil
+	}
+	if err := json.Unmarshal([]byte(form), schema); err != nil {
+		return nil, fmt.Errorf("表单 schema 不是有效的 JSON：%w", err)
+	}
+	return schema, nil
+}
+
+// ValidateTaskInput 按表单 schema 校验任务输入，输入必须是 JSON 对象
+func ValidateTaskInput(form string, input string) error {
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return &ValidationError{Fields: []FieldError{{Field: "input", Message: "输入不是有效的 JSON 对象"}}}
+	}
+	schema, err := ParseFormSchema(form)
+	if err != nil {
+		return err
+	}
+
+	var errs []FieldError
+	schema.validateObject("", value, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// requiredFields 返回对象上以数组形式声明的必填字段
+func (s *FormSchema) requiredFields() map[string]bool {
+	fields := map[string]bool{}
+	var names []string
+	if json.Unmarshal(s.Required, &names) == nil {
+		for _, name := range names {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// isRequired 判断字段本身是否声明为必填
+func (s *FormSchema) isRequired() bool {
+	var required bool
+	if json.Unmarshal(s.Required, &required) == nil && required {
+		return true
+	}
+	for _, rule := range s.Rules {
+		if rule.Required {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FormSchema) validateObject(path string, value map[string]interface{}, errs *[]FieldError) {
+	required := s.requiredFields()
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		field := s.Properties[name]
+		// void 类型是布局容器，子字段与容器处于同一层级
+		if field.Type == "void" {
+			field.validateObject(path, value, errs)
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		fieldValue, ok := value[name]
+		if !ok || isEmptyValue(fieldValue) {
+			if required[name] || field.isRequired() {
+				*errs = append(*errs, FieldError{Field: fieldPath, Message: "必填"})
+			}
+			continue
+		}
+		field.validate(fieldPath, fieldValue, errs)
+	}
+}
+
+func (s *FormSchema) validate(path string, value interface{}, errs *[]FieldError) {
+	addError := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// 先校验类型，类型不符时不再做其他校验
+	if message := s.typeError(value); message != "" {
+		addError("%s", message)
+		return
+	}
+	// 数组上的 enum 是多选的可选项，逐项检查
+	if len(s.Enum) > 0 {
+		candidates := []interface{}{value}
+		if items, ok := value.([]interface{}); ok {
+			candidates = items
+		}
+		for _, candidate := range candidates {
+			if !containsValue(s.Enum, candidate) {
+				addError("取值不在可选范围内")
+				return
+			}
+		}
+	}
+
+	minValue, maxValue := firstNumber(s.Min, s.Minimum), firstNumber(s.Max, s.Maximum)
+	for _, rule := range s.Rules {
+		minValue, maxValue = firstNumber(minValue, rule.Min), firstNumber(maxValue, rule.Max)
+	}
+
+	switch s.Type {
+	case "string", "date", "html":
+		str := value.(string)
+		length := float64(utf8.RuneCountInString(str))
+		if s.MinLength != nil {
+			minValue = firstNumber(minValue, floatPtr(float64(*s.MinLength)))
+		}
+		if s.MaxLength != nil {
+			maxValue = firstNumber(maxValue, floatPtr(float64(*s.MaxLength)))
+		}
+		if minValue != nil && length < *minValue {
+			addError("长度不能小于 %v", *minValue)
+		}
+		if maxValue != nil && length > *maxValue {
+			addError("长度不能大于 %v", *maxValue)
+		}
+		patterns := []FormRule{{Pattern: s.Pattern}}
+		patterns = append(patterns, s.Rules...)
+		for _, rule := range patterns {
+			if rule.Pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			if !re.MatchString(str) {
+				message := rule.Message
+				if message == "" {
+					message = "格式不正确"
+				}
+				addError("%s", message)
+			}
+		}
+	case "number", "integer":
+		num := value.(float64)
+		if s.Type == "integer" && num != float64(int64(num)) {
+			addError("应为整数")
+		}
+		if minValue != nil && num < *minValue {
+			addError("不能小于 %v", *minValue)
+		}
+		if maxValue != nil && num > *maxValue {
+			addError("不能大于 %v", *maxValue)
+		}
+	case "array", "range":
+		items := value.([]interface{})
+		if minValue != nil && float64(len(items)) < *minValue {
+			addError("至少需要 %v 项", *minValue)
+		}
+		if maxValue != nil && float64(len(items)) > *maxValue {
+			addError("最多允许 %v 项", *maxValue)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "object":
+		s.validateObject(path, value.(map[string]interface{}), errs)
+	}
+}
+
+// typeError 返回值与字段类型不符时的错误信息，未知类型不校验
+func (s *FormSchema) typeError(value interface{}) string {
+	switch s.Type {
+	case "string", "date", "html":
+		if _, ok := value.(string); !ok {
+			return "应为字符串"
+		}
+	case "number", "integer":
+		if _, ok := value.(float64); !ok {
+			return "应为数字"
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return "应为布尔值"
+		}
+	case "array", "range":
+		if _, ok := value.([]interface{}); !ok {
+			return "应为数组"
+		}
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return "应为对象"
+		}
+	}
+	return ""
+}
+
+// patternErrors 检查表单中所有 pattern 是否是合法的正则表达式
+func (s *FormSchema) patternErrors(path string, errs *[]FieldError) {
+	patterns := []string{s.Pattern}
+	for _, rule := range s.Rules {
+		patterns = append(patterns, rule.Pattern)
+	}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			*errs = append(*errs, FieldError{Field: "form", Message: fmt.Sprintf("%s 的 pattern 无效：%v", path, err)})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		field := s.Properties[name]
+		if field.Type == "void" {
+			field.patternErrors(path, errs)
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		field.patternErrors(fieldPath, errs)
+	}
+	if s.Items != nil {
+		s.Items.patternErrors(path+"[]", errs)
+	}
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNumber(values ...*float64) *float64 {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,80 @@
+package container
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testForm = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "required": true, "minLength": 2, "pattern": "^[a-z]+$"},
+		"level": {"type": "number", "enum": [1, 2, 3]},
+		"mode": {"type": "string", "enum": ["fast", "slow"]},
+		"targets": {"type": "array", "enum": ["a", "b"]},
+		"count": {"type": "integer", "min": 1, "max": 10},
+		"layout": {"type": "void", "properties": {"flag": {"type": "boolean"}}},
+		"options": {"type": "object", "properties": {"path": {"type": "string", "required": true}}}
+	}
+}`
+
+func TestValidateTaskInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		fields []string
+	}{
+		{"valid", `{"name":"ab","level":2,"mode":"fast","targets":["a","b"],"count":3,"flag":true,"options":{"path":"/"}}`, nil},
+		{"not an object", `[1]`, []string{"input"}},
+		{"required", `{}`, []string{"name"}},
+		{"too short and pattern", `{"name":"A"}`, []string{"name", "name"}},
+		{"enum typed compare", `{"name":"ab","level":"2"}`, []string{"level"}},
+		{"enum string", `{"name":"ab","mode":"medium"}`, []string{"mode"}},
+		{"type checked before enum", `{"name":"ab","mode":1}`, []string{"mode"}},
+		{"enum per array element", `{"name":"ab","targets":["a","c"]}`, []string{"targets"}},
+		{"array type", `{"name":"ab","targets":"a"}`, []string{"targets"}},
+		{"integer", `{"name":"ab","count":1.5}`, []string{"count"}},
+		{"range", `{"name":"ab","count":11}`, []string{"count"}},
+		{"void child", `{"name":"ab","flag":"yes"}`, []string{"flag"}},
+		{"nested required", `{"name":"ab","options":{}}`, []string{"options.path"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskInput(testForm, tt.input)
+			var fields []string
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				for _, field := range validationErr.Fields {
+					fields = append(fields, field.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields = %v, want %v (%v)", fields, tt.fields, err)
+			}
+		})
+	}
+}
+
+func TestValidateTaskConfigPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    string
+		wantErr bool
+	}{
+		{"valid pattern", `{"type":"object","properties":{"a":{"type":"string","pattern":"^a+$"}}}`, false},
+		{"invalid pattern", `{"type":"object","properties":{"a":{"type":"string","pattern":"(a"}}}`, true},
+		{"invalid rule pattern", `{"type":"object","properties":{"a":{"type":"string","rules":[{"pattern":"[a"}]}}}`, true},
+		{"invalid nested pattern", `{"type":"object","properties":{"o":{"type":"object","properties":{"b":{"type":"string","pattern":"*"}}}}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskConfig(&TaskConfig{TaskType: "a", Form: tt.form, RunEndpoint: "http://localhost/run"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package container
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 全文索引表及同步触发器
+//
+// task_search 使用 trigram 分词，可以按子串匹配中英文内容。任务输出被保留策略清理后
+// 索引仍然保留，已归档的日志依旧可以搜索；删除任务时一并删除其索引。
+//
+// task_id 列不建索引，触发器按 rowid 定位索引行：输入为 -(id*2)，结果为 -(id*2+1)，
+// 输出为 task_output.id。输出行在 task_search_output 中记录所属任务，任务输出被清理后
+// 仍能在删除任务时找到对应的索引行。
+//
+// 输入包含敏感字段，不由触发器索引，创建任务时由 indexTaskInput 写入脱敏后的输入。
+var searchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS task_search USING fts5(task_id UNINDEXED, source UNINDEXED, content, tokenize = 'trigram')`,
+	`CREATE TABLE IF NOT EXISTS task_search_output (rowid INTEGER PRIMARY KEY, task_id INTEGER NOT NULL)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_search_output_task_id ON task_search_output(task_id)`,
+	`CREATE TRIGGER IF NOT EXISTS task_search_output_insert AFTER INSERT ON task_output BEGIN
+		INSERT INTO task_search(rowid, task_id, source, content) VALUES (new.id, new.task_id, 'output', new.output);
+		INSERT INTO task_search_output(rowid, task_id) VALUES (new.id, new.task_id);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS task_search_task_insert AFTER INSERT ON task BEGIN
+		INSERT INTO task_search(rowid, task_id, source, content) VALUES (-(new.id * 2 + 1), new.id, 'result', new.result);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS task_search_result_update AFTER UPDATE OF result ON task BEGIN
+		DELETE FROM task_search WHERE rowid = -(old.id * 2 + 1);
+		INSERT INTO task_search(rowid, task_id, source, content) VALUES (-(new.id * 2 + 1), new.id, 'result', new.result);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS task_search_task_delete AFTER DELETE ON task BEGIN
+		DELETE FROM task_search WHERE rowid IN (-(old.id * 2), -(old.id * 2 + 1));
+		DELETE FROM task_search WHERE rowid IN (SELECT rowid FROM task_search_output WHERE task_id = old.id);
+		DELETE FROM task_search_output WHERE task_id = old.id;
+	END`,
+}
+
+// initSearchIndex 创建全文索引，首次创建时为已有数据建立索引
+func initSearchIndex() error {
+	var exists int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'task_search'").Scan(&exists).Error; err != nil {
+		return err
+	}
+	for _, stmt := range searchSchema {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	var tasks []*Task
+	err := db.Select("id", "input", "sensitive_fields").FindInBatches(&tasks, 1000, func(*gorm.DB, int) error {
+		for _, task := range tasks {
+			if err := indexTaskInput(db, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	backfill := []string{
+		`INSERT INTO task_search(rowid, task_id, source, content) SELECT -(id * 2 + 1), id, 'result', result FROM task`,
+		`INSERT INTO task_search(rowid, task_id, source, content) SELECT id, task_id, 'output', output FROM task_output`,
+		`INSERT INTO task_search_output(rowid, task_id) SELECT id, task_id FROM task_output`,
+	}
+	for _, stmt := range backfill {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexTaskInput 为新任务的输入建立索引，敏感字段脱敏后再写入，需与创建任务在同一事务中调用
+func indexTaskInput(tx *gorm.DB, task *Task) error {
+	input := RedactTaskInput(task.Input, task.SensitiveFields)
+	return tx.Exec("INSERT INTO task_search(rowid, task_id, source, content) VALUES (?, ?, 'input', ?)", -int64(task.ID)*2, task.ID, input).Error
+}
+
+// SearchFilter 全文搜索条件
+type SearchFilter struct {
+	Query     string
+	TaskType  string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// SearchSnippet 命中内容的高亮片段
+type SearchSnippet struct {
+	Source  string `json:"source"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchResult 搜索命中的任务及其片段
+type SearchResult struct {
+	*Task
+	Snippets []SearchSnippet `json:"snippets"`
+}
+
+// MarshalJSON 内嵌的 Task 自定义了序列化，这里需要把片段合并回任务字段中
+func (r SearchResult) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	if r.Task != nil {
+		data, err := json.Marshal(r.Task)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+	}
+	fields["snippets"] = r.Snippets
+	return json.Marshal(fields)
+}
+
+// 每个任务最多返回的片段数
+const maxSearchSnippets = 3
+
+// 片段中标记命中位置的占位符，转义内容后再替换为 <mark>
+const (
+	snippetMarkStart = "\x02"
+	snippetMarkEnd   = "\x03"
+)
+
+// snippetReplacer 将占位符还原为高亮标签
+var snippetReplacer = strings.NewReplacer(snippetMarkStart, "<mark>", snippetMarkEnd, "</mark>")
+
+// escapeSnippet 转义片段中的 HTML，只保留命中位置的 <mark> 标签
+func escapeSnippet(snippet string) string {
+	return snippetReplacer.Replace(html.EscapeString(snippet))
+}
+
+// buildMatchQuery 将用户输入按空白拆分，每个词作为短语匹配，避免 FTS 语法错误
+func buildMatchQuery(query string) string {
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// SearchTasks 全文搜索任务输入、结果与输出，按任务创建时间倒序分页
+func SearchTasks(filter SearchFilter, page int, pageSize int) ([]*SearchResult, int64, error) {
+	match := buildMatchQuery(filter.Query)
+
+	query := db.Table("task_search").
+		Joins("JOIN task ON task.id = task_search.task_id").
+		Where("task_search MATCH ?", match)
+	if filter.TaskType != "" {
+		query = query.Where("task.task_type = ?", filter.TaskType)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("task.create_time >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("task.create_time < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Distinct("task_search.task_id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var taskIDs []uint
+	err := query.Session(&gorm.Session{}).
+		Select("task.id").
+		Group("task.id").
+		Order("task.create_time DESC").
+		Offset((page-1)*pageSize).
+		Limit(pageSize).
+		Pluck("task.id", &taskIDs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(taskIDs) == 0 {
+		return []*SearchResult{}, total, nil
+	}
+
+	var tasks []*Task
+	if err := db.Where("id IN ?", taskIDs).Order("create_time DESC").Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+	results := make([]*SearchResult, 0, len(tasks))
+	resultMap := map[uint]*SearchResult{}
+	for _, task := range tasks {
+		result := &SearchResult{Task: task, Snippets: []SearchSnippet{}}
+		results = append(results, result)
+		resultMap[task.ID] = result
+	}
+
+	var hits []struct {
+		TaskID  uint
+		Source  string
+		Snippet string
+	}
+	err = db.Table("task_search").
+		Select("task_id, source, snippet(task_search, 2, ?, ?, '...', 16) AS snippet", snippetMarkStart, snippetMarkEnd).
+		Where("task_search MATCH ? AND task_id IN ?", match, taskIDs).
+		Order("rank").
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, hit := range hits {
+		result, ok := resultMap[hit.TaskID]
+		if !ok || len(result.Snippets) >= maxSearchSnippets {
+			continue
+		}
+		result.Snippets = append(result.Snippets, SearchSnippet{Source: hit.Source, Snippet: escapeSnippet(hit.Snippet)})
+	}
+	return results, total, nil
+}
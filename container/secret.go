@@ -0,0 +1,165 @@
+package container
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"run-task/util"
+	"time"
+)
+
+// TaskSecret 加密保存的密钥，对应 task_secret 表
+//
+// TaskType 为空时为全局密钥，否则只对该任务类型可见；同名时任务类型的密钥优先。
+// Value 为密文，不会出现在任何接口的响应中。
+type TaskSecret struct {
+	ID         uint      `gorm:"autoIncrement;column:id" json:"id"`
+	Name       string    `gorm:"column:name;uniqueIndex:idx_task_secret_scope" json:"name"`
+	TaskType   string    `gorm:"column:task_type;uniqueIndex:idx_task_secret_scope" json:"task_type"`
+	Value      string    `gorm:"column:value" json:"-"`
+	CreateTime time.Time `gorm:"column:create_time" json:"create_time"`
+	UpdateTime time.Time `gorm:"column:update_time" json:"update_time"`
+}
+
+func (TaskSecret) TableName() string {
+	return "task_secret"
+}
+
+var (
+	ErrSecretKeyMissing  = errors.New("未配置 SECRET_KEY，无法使用密钥库")
+	ErrTaskSecretInvalid = errors.New("密钥名称只能包含字母、数字、下划线、中划线和点")
+	secretNamePattern    = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+func secretKey() ([]byte, error) {
+	key := GetConfig().SecretKey
+	if key == "" {
+		return nil, ErrSecretKeyMissing
+	}
+	return util.DeriveKey(key), nil
+}
+
+// SaveTaskSecret 加密后保存密钥，同名同作用域的密钥会被覆盖
+func SaveTaskSecret(name string, taskType string, value string) error {
+	if !secretNamePattern.MatchString(name) {
+		return ErrTaskSecretInvalid
+	}
+	key, err := secretKey()
+	if err != nil {
+		return err
+	}
+	encrypted, err := util.EncryptString(key, value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var existing TaskSecret
+	if err := db.Where("name = ? AND task_type = ?", name, taskType).First(&existing).Error; err == nil {
+		return db.Model(&existing).Updates(map[string]interface{}{"value": encrypted, "update_time": now}).Error
+	}
+	return db.Create(&TaskSecret{
+		Name:       name,
+		TaskType:   taskType,
+		Value:      encrypted,
+		CreateTime: now,
+		UpdateTime: now,
+	}).Error
+}
+
+// ListTaskSecrets 列出密钥名称，taskType 不为空时只列出该任务类型与全局的密钥
+func ListTaskSecrets(taskType string) ([]*TaskSecret, error) {
+	var secrets []*TaskSecret
+	query := db.Order("task_type ASC, name ASC")
+	if taskType != "" {
+		query = query.Where("task_type IN ?", []string{"", taskType})
+	}
+	if err := query.Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// DeleteTaskSecret 删除密钥
+func DeleteTaskSecret(name string, taskType string) error {
+	return db.Where("name = ? AND task_type = ?", name, taskType).Delete(&TaskSecret{}).Error
+}
+
+// ResolveTaskSecrets 解密任务类型可用的全部密钥，任务类型的密钥覆盖同名的全局密钥
+func ResolveTaskSecrets(taskType string) (map[string]string, error) {
+	var secrets []*TaskSecret
+	if err := db.Where("task_type IN ?", []string{"", taskType}).Order("task_type ASC").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return map[string]string{}, nil
+	}
+	key, err := secretKey()
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, secret := range secrets {
+		value, err := util.DecryptString(key, secret.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[secret.Name] = value
+	}
+	return values, nil
+}
+
+// secretRefPattern 输入中对密钥的引用，由模板函数 secret 生成
+var secretRefPattern = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.-]+)\}`)
+
+// secretRefOnlyPattern 整个值只是一个密钥引用
+var secretRefOnlyPattern = regexp.MustCompile(`^\$\{secret:[A-Za-z0-9_.-]+\}$`)
+
+// isSecretRef 判断值是否只是一个密钥引用
+func isSecretRef(value string) bool {
+	return secretRefOnlyPattern.MatchString(value)
+}
+
+// SecretRef 返回密钥引用，任务输入中只保存引用，执行端拉取输入时才替换为明文
+func SecretRef(name string) string {
+	return "${secret:" + name + "}"
+}
+
+// ReplaceSecretRefs 将文本中的密钥引用替换为明文，未知的引用保持原样
+func ReplaceSecretRefs(text string, secrets map[string]string) string {
+	return secretRefPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := secretRefPattern.FindStringSubmatch(ref)[1]
+		if value, ok := secrets[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// VerifyTaskToken 校验执行端携带的任务令牌
+func VerifyTaskToken(task *Task, token string) bool {
+	return task.Token != "" && subtle.ConstantTimeCompare([]byte(task.Token), []byte(token)) == 1
+}
+
+// ResolveTaskInput 返回解密了敏感字段并替换了密钥引用的任务输入，只在执行端凭令牌拉取输入时使用
+func ResolveTaskInput(task *Task) (string, error) {
+	input, err := decryptTaskInput(task.Input)
+	if err != nil {
+		return "", err
+	}
+	if !secretRefPattern.MatchString(input) {
+		return input, nil
+	}
+	secrets, err := ResolveTaskSecrets(task.TaskType)
+	if err != nil {
+		return "", err
+	}
+	// 输入是 JSON，明文需要按 JSON 字符串转义后再替换
+	escaped := make(map[string]string, len(secrets))
+	for name, value := range secrets {
+		data, _ := json.Marshal(value)
+		escaped[name] = string(data[1 : len(data)-1])
+	}
+	return ReplaceSecretRefs(input, escaped), nil
+}
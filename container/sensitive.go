@@ -0,0 +1,319 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"run-task/util"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// encryptedValuePrefix 存储时加密的敏感字段值前缀
+	encryptedValuePrefix = "enc:"
+	// RedactedValue 接口响应中敏感字段的替代值
+	RedactedValue = "******"
+	// minMaskLength 短于该长度的敏感值不在输出中遮盖，避免误伤正常输出
+	minMaskLength = 3
+)
+
+// isSensitive 判断字段是否为敏感字段，只有显式声明 sensitive 的字段才加密
+func (s *FormSchema) isSensitive() bool {
+	return s.Sensitive
+}
+
+// sensitivePaths 返回表单中所有敏感字段的路径，每个路径是逐层的字段名
+func (s *FormSchema) sensitivePaths(prefix []string, paths *[][]string) {
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		field := s.Properties[name]
+		if field.Type == "void" {
+			field.sensitivePaths(prefix, paths)
+			continue
+		}
+		path := append(slices.Clone(prefix), name)
+		if field.isSensitive() {
+			*paths = append(*paths, path)
+			continue
+		}
+		if field.Type == "object" {
+			field.sensitivePaths(path, paths)
+		}
+	}
+}
+
+// SensitiveFieldPaths 解析表单 schema 中声明的敏感字段
+func SensitiveFieldPaths(form string) [][]string {
+	schema, err := ParseFormSchema(form)
+	if err != nil {
+		return nil
+	}
+	var paths [][]string
+	schema.sensitivePaths(nil, &paths)
+	return paths
+}
+
+// transformPath 对 JSON 对象中指定路径上的值调用 fn，路径不存在时忽略
+func transformPath(obj map[string]interface{}, path []string, fn func(interface{}) (interface{}, error)) error {
+	for _, name := range path[:len(path)-1] {
+		next, ok := obj[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = next
+	}
+	last := path[len(path)-1]
+	value, ok := obj[last]
+	if !ok || isEmptyValue(value) {
+		return nil
+	}
+	transformed, err := fn(value)
+	if err != nil {
+		return err
+	}
+	obj[last] = transformed
+	return nil
+}
+
+// transformSensitiveInput 对输入中的敏感字段逐个调用 fn，输入不是 JSON 对象时原样返回
+func transformSensitiveInput(input string, paths [][]string, fn func(interface{}) (interface{}, error)) (string, error) {
+	if len(paths) == 0 || strings.TrimSpace(input) == "" {
+		return input, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &obj); err != nil {
+		return input, nil
+	}
+	for _, path := range paths {
+		if err := transformPath(obj, path, fn); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// encryptSensitiveInput 加密输入中的敏感字段，值以 JSON 编码后整体加密
+func encryptSensitiveInput(input string, paths [][]string) (string, error) {
+	return transformSensitiveInput(input, paths, func(value interface{}) (interface{}, error) {
+		if str, ok := value.(string); ok && strings.HasPrefix(str, encryptedValuePrefix) {
+			return str, nil
+		}
+		key, err := secretKey()
+		if errors.Is(err, ErrSecretKeyMissing) {
+			// 未配置 SECRET_KEY 时明文保存，启动时已输出警告
+			return value, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(value)
+		encrypted, err := util.EncryptString(key, string(data))
+		if err != nil {
+			return nil, err
+		}
+		return encryptedValuePrefix + encrypted, nil
+	})
+}
+
+// walkEncryptedValues 对输入中所有已加密的值调用 fn，不依赖当前表单，表单修改后旧任务仍能处理
+func walkEncryptedValues(input string, fn func(string) (interface{}, error)) (string, error) {
+	if !strings.Contains(input, encryptedValuePrefix) {
+		return input, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return input, nil
+	}
+	var walk func(interface{}) (interface{}, error)
+	walk = func(v interface{}) (interface{}, error) {
+		switch v := v.(type) {
+		case string:
+			if strings.HasPrefix(v, encryptedValuePrefix) {
+				return fn(strings.TrimPrefix(v, encryptedValuePrefix))
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				walked, err := walk(item)
+				if err != nil {
+					return nil, err
+				}
+				v[key] = walked
+			}
+		case []interface{}:
+			for i, item := range v {
+				walked, err := walk(item)
+				if err != nil {
+					return nil, err
+				}
+				v[i] = walked
+			}
+		}
+		return v, nil
+	}
+	walked, err := walk(value)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(walked)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RedactTaskInput 将输入中 paths 上的敏感字段与其余加密的值替换为 ******
+//
+// 按路径脱敏覆盖未配置 SECRET_KEY 时明文保存的字段，按前缀脱敏覆盖表单修改前加密的字段。
+func RedactTaskInput(input string, paths [][]string) string {
+	redacted, err := transformSensitiveInput(input, paths, func(interface{}) (interface{}, error) {
+		return RedactedValue, nil
+	})
+	if err != nil {
+		return RedactedValue
+	}
+	redacted, err = walkEncryptedValues(redacted, func(string) (interface{}, error) {
+		return RedactedValue, nil
+	})
+	if err != nil {
+		return RedactedValue
+	}
+	return redacted
+}
+
+// decryptTaskInput 解密输入中的敏感字段
+func decryptTaskInput(input string) (string, error) {
+	return walkEncryptedValues(input, func(encrypted string) (interface{}, error) {
+		key, err := secretKey()
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := util.DecryptString(key, encrypted)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(plaintext), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	})
+}
+
+// encryptTaskSensitiveFields 按任务类型的表单加密输入与输入模板中的敏感字段
+func encryptTaskSensitiveFields(task *Task, form string) error {
+	paths := SensitiveFieldPaths(form)
+	if len(paths) == 0 {
+		return nil
+	}
+	input, err := encryptSensitiveInput(task.Input, paths)
+	if err != nil {
+		return err
+	}
+	inputTemplate, err := encryptSensitiveInput(task.InputTemplate, paths)
+	if err != nil {
+		return err
+	}
+	task.Input, task.InputTemplate = input, inputTemplate
+	task.SensitiveFields = paths
+	return nil
+}
+
+// outputMasks 缓存每个任务需要在输出中遮盖的明文
+var outputMasks sync.Map
+
+// outputMaskEntry 一个任务的遮盖明文及最近一次使用的时间
+type outputMaskEntry struct {
+	masks    []string
+	lastUsed atomic.Int64
+}
+
+// taskOutputMasks 返回任务的敏感字段与引用密钥的明文，结果按任务缓存，任务不存在时 found 为 false
+func taskOutputMasks(taskID uint) (masks []string, found bool) {
+	if value, ok := outputMasks.Load(taskID); ok {
+		entry := value.(*outputMaskEntry)
+		entry.lastUsed.Store(time.Now().UnixNano())
+		return entry.masks, true
+	}
+	task := GetTask(taskID)
+	if task == nil {
+		return nil, false
+	}
+	addMask := func(value string) {
+		if len(value) >= minMaskLength {
+			masks = append(masks, value)
+		}
+	}
+	// 未配置 SECRET_KEY 时敏感字段明文保存，按路径取值
+	transformSensitiveInput(task.Input, task.SensitiveFields, func(value interface{}) (interface{}, error) {
+		if str, ok := value.(string); ok {
+			if !strings.HasPrefix(str, encryptedValuePrefix) {
+				addMask(str)
+			}
+		} else if data, err := json.Marshal(value); err == nil {
+			addMask(string(data))
+		}
+		return value, nil
+	})
+	walkEncryptedValues(task.Input, func(encrypted string) (interface{}, error) {
+		if key, err := secretKey(); err == nil {
+			if plaintext, err := util.DecryptString(key, encrypted); err == nil {
+				var value interface{}
+				json.Unmarshal([]byte(plaintext), &value)
+				if str, ok := value.(string); ok {
+					addMask(str)
+				} else {
+					addMask(plaintext)
+				}
+			}
+		}
+		return nil, nil
+	})
+	if refs := secretRefPattern.FindAllStringSubmatch(task.Input, -1); len(refs) > 0 {
+		if secrets, err := ResolveTaskSecrets(task.TaskType); err == nil {
+			for _, ref := range refs {
+				addMask(secrets[ref[1]])
+			}
+		}
+	}
+	// 先替换较长的值，避免较短的值破坏包含它的较长值
+	slices.SortFunc(masks, func(a, b string) int { return len(b) - len(a) })
+	entry := &outputMaskEntry{masks: masks}
+	entry.lastUsed.Store(time.Now().UnixNano())
+	outputMasks.Store(taskID, entry)
+	return masks, true
+}
+
+// MaskTaskOutput 遮盖输出中出现的敏感字段与密钥明文
+func MaskTaskOutput(taskID uint, output string) string {
+	masks, _ := taskOutputMasks(taskID)
+	return maskOutput(masks, output)
+}
+
+func maskOutput(masks []string, output string) string {
+	for _, mask := range masks {
+		output = strings.ReplaceAll(output, mask, RedactedValue)
+	}
+	return output
+}
+
+// forgetOutputMasks 任务结束或删除后清除缓存的明文
+func forgetOutputMasks(taskID uint) {
+	outputMasks.Delete(taskID)
+}
+
+// evictIdleOutputMasks 清除 idleBefore 之后没有使用过的明文，没有回调结束的任务不会一直占用内存，再次使用时重新计算
+func evictIdleOutputMasks(idleBefore time.Time) {
+	outputMasks.Range(func(key, value interface{}) bool {
+		if value.(*outputMaskEntry).lastUsed.Load() < idleBefore.UnixNano() {
+			outputMasks.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
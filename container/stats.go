@@ -0,0 +1,274 @@
+package container
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 统计的时间粒度
+const (
+	StatsBucketHour = "hour"
+	StatsBucketDay  = "day"
+	StatsBucketWeek = "week"
+)
+
+// histogramGrowth 耗时直方图相邻分桶的比例，百分位返回分桶上界，相对误差不超过 10%
+const histogramGrowth = 1.1
+
+// statsBatchSize 从任务表聚合时每批读取的行数
+const statsBatchSize = 1000
+
+// histogram 按对数分桶的耗时直方图（毫秒），分桶可以直接相加，适合合并预聚合结果
+type histogram map[int]int64
+
+func histogramIndex(ms int64) int {
+	if ms <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(ms)) / math.Log(histogramGrowth)))
+}
+
+func (h histogram) add(ms int64) {
+	h[histogramIndex(max(ms, 0))]++
+}
+
+func (h histogram) merge(other histogram) {
+	for index, count := range other {
+		h[index] += count
+	}
+}
+
+// percentile 返回第 p 百分位所在分桶的上界
+func (h histogram) percentile(p float64) *float64 {
+	var total int64
+	for _, count := range h {
+		total += count
+	}
+	if total == 0 {
+		return nil
+	}
+	rank := int64(math.Ceil(p / 100 * float64(total)))
+	var seen int64
+	for _, index := range slices.Sorted(maps.Keys(h)) {
+		seen += h[index]
+		if seen >= rank {
+			value := math.Round(math.Pow(histogramGrowth, float64(index)))
+			return &value
+		}
+	}
+	return nil
+}
+
+// statsAggregate 一个任务类型在一段时间内的聚合结果
+type statsAggregate struct {
+	Counts    map[string]int64
+	Duration  histogram
+	QueueWait histogram
+}
+
+func newStatsAggregate() *statsAggregate {
+	return &statsAggregate{Counts: map[string]int64{}, Duration: histogram{}, QueueWait: histogram{}}
+}
+
+func (a *statsAggregate) merge(other *statsAggregate) {
+	for status, count := range other.Counts {
+		a.Counts[status] += count
+	}
+	a.Duration.merge(other.Duration)
+	a.QueueWait.merge(other.QueueWait)
+}
+
+// statsKey 按任务类型与时间分桶的聚合键
+type statsKey struct {
+	TaskType string
+	Start    time.Time
+}
+
+// statsRow 聚合需要的任务字段
+type statsRow struct {
+	ID           uint
+	TaskType     string
+	Status       string
+	CreateTime   time.Time
+	DispatchedAt *time.Time
+	StartedAt    *time.Time
+	DurationMs   *int64
+}
+
+// add 计入一个任务；排队等待为创建到开始执行的时间，没有开始时间时以下发时间计算
+func (a *statsAggregate) add(row *statsRow) {
+	a.Counts[row.Status]++
+	if row.DurationMs != nil {
+		a.Duration.add(*row.DurationMs)
+	}
+	start := row.StartedAt
+	if start == nil {
+		start = row.DispatchedAt
+	}
+	if start != nil {
+		a.QueueWait.add(start.Sub(row.CreateTime).Milliseconds())
+	}
+}
+
+// aggregateHourly 直接从任务表按任务类型与小时聚合 [start, end) 内创建的任务
+func aggregateHourly(taskType string, start, end time.Time) (map[statsKey]*statsAggregate, error) {
+	result := map[statsKey]*statsAggregate{}
+	query := db.Model(&Task{}).
+		Select("id", "task_type", "status", "create_time", "dispatched_at", "started_at", "duration_ms").
+		Where("create_time >= ? AND create_time < ?", start, end)
+	if taskType != "" {
+		query = query.Where("task_type = ?", taskType)
+	}
+	var rows []*statsRow
+	err := query.FindInBatches(&rows, statsBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, row := range rows {
+			key := statsKey{TaskType: row.TaskType, Start: row.CreateTime.Truncate(time.Hour)}
+			if result[key] == nil {
+				result[key] = newStatsAggregate()
+			}
+			result[key].add(row)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// bucketStart 返回时间所在分桶的开始时间，天与周按本地时间划分，周从周一开始
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.Local()
+	switch bucket {
+	case StatsBucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case StatsBucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return t.Truncate(time.Hour)
+	}
+}
+
+// StatsFilter 统计条件，时间范围按小时对齐
+type StatsFilter struct {
+	TaskType  string
+	Bucket    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Percentiles 耗时百分位，单位毫秒，Count 为参与计算的任务数
+type Percentiles struct {
+	Count int64    `json:"count"`
+	P50   *float64 `json:"p50"`
+	P95   *float64 `json:"p95"`
+	P99   *float64 `json:"p99"`
+}
+
+func newPercentiles(h histogram) Percentiles {
+	var count int64
+	for _, c := range h {
+		count += c
+	}
+	return Percentiles{Count: count, P50: h.percentile(50), P95: h.percentile(95), P99: h.percentile(99)}
+}
+
+// StatsBucket 一个任务类型在一个时间分桶内的统计
+//
+// SuccessRate 为 success 在已结束任务中的占比，没有已结束任务时为空；
+// 百分位基于对数分桶的直方图，是相对误差不超过 5% 的近似值。
+type StatsBucket struct {
+	TaskType    string           `json:"task_type"`
+	BucketStart time.Time        `json:"bucket_start"`
+	Total       int64            `json:"total"`
+	Counts      map[string]int64 `json:"counts"`
+	SuccessRate *float64         `json:"success_rate"`
+	Duration    Percentiles      `json:"duration"`
+	QueueWait   Percentiles      `json:"queue_wait"`
+}
+
+// GetTaskStats 按任务类型与时间分桶统计任务数量、成功率、耗时与排队等待
+//
+// 开启预聚合时，已汇总的小时直接读取汇总结果，其余时间从任务表实时计算。
+func GetTaskStats(filter StatsFilter) ([]*StatsBucket, error) {
+	switch filter.Bucket {
+	case StatsBucketHour, StatsBucketDay, StatsBucketWeek:
+	default:
+		return nil, &ValidationError{Fields: []FieldError{{Field: "bucket", Message: "只支持 hour、day 或 week"}}}
+	}
+	start, end := filter.StartTime.Truncate(time.Hour), filter.EndTime
+	if end.Truncate(time.Hour) != end {
+		end = end.Truncate(time.Hour).Add(time.Hour)
+	}
+	if !start.Before(end) {
+		return nil, &ValidationError{Fields: []FieldError{{Field: "start_time", Message: "开始时间必须早于结束时间"}}}
+	}
+
+	hourly := map[statsKey]*statsAggregate{}
+	rawStart := start
+	if GetConfig().StatsRollup {
+		rolledUpTo, err := statsRolledUpTo()
+		if err != nil {
+			return nil, err
+		}
+		if rolledUpTo.After(start) {
+			rollupEnd := rolledUpTo
+			if end.Before(rollupEnd) {
+				rollupEnd = end
+			}
+			if hourly, err = loadStatsRollups(filter.TaskType, start, rollupEnd); err != nil {
+				return nil, err
+			}
+			rawStart = rollupEnd
+		}
+	}
+	if rawStart.Before(end) {
+		raw, err := aggregateHourly(filter.TaskType, rawStart, end)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(hourly, raw)
+	}
+
+	buckets := map[statsKey]*statsAggregate{}
+	for key, aggregate := range hourly {
+		bucketKey := statsKey{TaskType: key.TaskType, Start: bucketStart(key.Start, filter.Bucket)}
+		if buckets[bucketKey] == nil {
+			buckets[bucketKey] = newStatsAggregate()
+		}
+		buckets[bucketKey].merge(aggregate)
+	}
+
+	result := make([]*StatsBucket, 0, len(buckets))
+	for key, aggregate := range buckets {
+		bucket := &StatsBucket{
+			TaskType:    key.TaskType,
+			BucketStart: key.Start,
+			Counts:      aggregate.Counts,
+			Duration:    newPercentiles(aggregate.Duration),
+			QueueWait:   newPercentiles(aggregate.QueueWait),
+		}
+		var finished int64
+		for status, count := range aggregate.Counts {
+			bucket.Total += count
+			if IsTerminalStatus(status) {
+				finished += count
+			}
+		}
+		if finished > 0 {
+			rate := float64(aggregate.Counts[TaskStatusSuccess]) / float64(finished)
+			bucket.SuccessRate = &rate
+		}
+		result = append(result, bucket)
+	}
+	slices.SortFunc(result, func(a, b *StatsBucket) int {
+		return cmp.Or(cmp.Compare(a.TaskType, b.TaskType), a.BucketStart.Compare(b.BucketStart))
+	})
+	return result, nil
+}
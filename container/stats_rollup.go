@@ -0,0 +1,146 @@
+package container
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskStatsRollup 按任务类型与小时预聚合的统计，对应 task_stats_rollup 表
+//
+// 汇总后删除的任务仍计入统计；只汇总所有任务都已结束的小时（长时间未结束的任务除外），汇总后的结果不再变化。
+type TaskStatsRollup struct {
+	TaskType   string           `gorm:"column:task_type;primaryKey"`
+	Hour       time.Time        `gorm:"column:hour;primaryKey"`
+	Counts     map[string]int64 `gorm:"column:counts;serializer:json"`
+	Duration   histogram        `gorm:"column:duration;serializer:json"`
+	QueueWait  histogram        `gorm:"column:queue_wait;serializer:json"`
+	UpdateTime time.Time        `gorm:"column:update_time"`
+}
+
+func (TaskStatsRollup) TableName() string {
+	return "task_stats_rollup"
+}
+
+// TaskStatsRollupState 预聚合的进度，RolledUpTo 之前的小时都已汇总
+type TaskStatsRollupState struct {
+	ID         uint      `gorm:"column:id;primaryKey"`
+	RolledUpTo time.Time `gorm:"column:rolled_up_to"`
+}
+
+func (TaskStatsRollupState) TableName() string {
+	return "task_stats_rollup_state"
+}
+
+// statsRollupChunk 每个事务汇总的时间跨度，首次开启时分批补齐历史
+const statsRollupChunk = 7 * 24 * time.Hour
+
+// statsRolledUpTo 返回已汇总到的时间，尚未汇总过时为零值
+func statsRolledUpTo() (time.Time, error) {
+	var state TaskStatsRollupState
+	err := db.Where("id = 1").Limit(1).Find(&state).Error
+	return state.RolledUpTo, err
+}
+
+func loadStatsRollups(taskType string, start, end time.Time) (map[statsKey]*statsAggregate, error) {
+	query := db.Where("hour >= ? AND hour < ?", start, end)
+	if taskType != "" {
+		query = query.Where("task_type = ?", taskType)
+	}
+	var rollups []*TaskStatsRollup
+	if err := query.Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	result := map[statsKey]*statsAggregate{}
+	for _, rollup := range rollups {
+		aggregate := newStatsAggregate()
+		aggregate.merge(&statsAggregate{Counts: rollup.Counts, Duration: rollup.Duration, QueueWait: rollup.QueueWait})
+		result[statsKey{TaskType: rollup.TaskType, Start: rollup.Hour}] = aggregate
+	}
+	return result, nil
+}
+
+// StartStatsRollup 开启预聚合时启动后台任务，定期汇总早于 StatsRollupDelay 的小时
+func StartStatsRollup() {
+	cfg := GetConfig()
+	if !cfg.StatsRollup || cfg.StatsRollupInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			if err := RollupTaskStats(); err != nil {
+				log.Printf("rollup task stats failed: %v", err)
+			}
+			time.Sleep(cfg.StatsRollupInterval)
+		}
+	}()
+}
+
+// RollupTaskStats 汇总上次进度之后、早于 StatsRollupDelay 的完整小时
+//
+// 汇总在最早的未结束任务所在的小时前停止，这些小时继续从任务表实时统计，任务结束后的下一轮再汇总。
+// 创建超过 StatsRollupMaxPending 仍未结束的任务不再等待，按当前状态汇总。
+func RollupTaskStats() error {
+	cfg := GetConfig()
+	now := time.Now()
+	cutoff := now.Add(-cfg.StatsRollupDelay).Truncate(time.Hour)
+	from, err := statsRolledUpTo()
+	if err != nil {
+		return err
+	}
+	var pending Task
+	err = db.Select("create_time").
+		Where("status IN ? AND create_time < ? AND create_time >= ?", []string{TaskStatusQueued, TaskStatusReady, TaskStatusRunning}, cutoff, now.Add(-cfg.StatsRollupMaxPending)).
+		Order("create_time ASC").Limit(1).Find(&pending).Error
+	if err != nil {
+		return err
+	}
+	if !pending.CreateTime.IsZero() && pending.CreateTime.Truncate(time.Hour).Before(cutoff) {
+		cutoff = pending.CreateTime.Truncate(time.Hour)
+	}
+	if from.IsZero() {
+		var first Task
+		if err := db.Select("create_time").Order("create_time ASC").Limit(1).Find(&first).Error; err != nil {
+			return err
+		}
+		if first.CreateTime.IsZero() {
+			from = cutoff
+		} else {
+			from = first.CreateTime.Truncate(time.Hour)
+		}
+	}
+
+	for from.Before(cutoff) {
+		to := from.Add(statsRollupChunk)
+		if to.After(cutoff) {
+			to = cutoff
+		}
+		hourly, err := aggregateHourly("", from, to)
+		if err != nil {
+			return err
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for key, aggregate := range hourly {
+				rollup := &TaskStatsRollup{
+					TaskType:   key.TaskType,
+					Hour:       key.Start,
+					Counts:     aggregate.Counts,
+					Duration:   aggregate.Duration,
+					QueueWait:  aggregate.QueueWait,
+					UpdateTime: now,
+				}
+				if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(rollup).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&TaskStatsRollupState{ID: 1, RolledUpTo: to}).Error
+		})
+		if err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
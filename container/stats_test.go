@@ -0,0 +1,61 @@
+package container
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	uniform := histogram{}
+	for ms := int64(1); ms <= 1000; ms++ {
+		uniform.add(ms)
+	}
+	tests := []struct {
+		name string
+		h    histogram
+		p    float64
+		want float64 // 0 表示没有数据
+	}{
+		{"empty", histogram{}, 50, 0},
+		{"uniform p50", uniform, 50, 500},
+		{"uniform p95", uniform, 95, 950},
+		{"uniform p99", uniform, 99, 990},
+		{"zero in first bucket", histogram{histogramIndex(0): 2}, 50, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.h.percentile(tt.p)
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("percentile = %v, want nil", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("percentile = nil")
+			}
+			// 分桶上界的相对误差不超过 histogramGrowth - 1
+			if math.Abs(*got-tt.want)/tt.want > histogramGrowth-1 {
+				t.Errorf("percentile = %v, want about %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, b, all := histogram{}, histogram{}, histogram{}
+	for ms := int64(0); ms < 500; ms += 7 {
+		a.add(ms)
+		all.add(ms)
+	}
+	for ms := int64(500); ms < 5000; ms += 13 {
+		b.add(ms)
+		all.add(ms)
+	}
+	a.merge(b)
+	for _, p := range []float64{50, 95, 99} {
+		if *a.percentile(p) != *all.percentile(p) {
+			t.Errorf("p%v of merged = %v, want %v", p, *a.percentile(p), *all.percentile(p))
+		}
+	}
+}
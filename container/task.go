@@ -1,9 +1,14 @@
 package container
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"run-task/util"
+
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 )
@@ -13,13 +18,37 @@ var db *gorm.DB
 // Task 定义Task结构体，对应task表
 type Task struct {
 	ID          uint      `gorm:"autoIncrement;column:id" json:"id"`
-	TaskType    string    `gorm:"column:task_type" json:"task_type"`
+	TaskType    string    `gorm:"column:task_type;index:idx_task_type_create_time,priority:1" json:"task_type"`
 	Input       string    `gorm:"column:input" json:"input"`
-	CreateTime  time.Time `gorm:"column:create_time" json:"create_time"`
+	CreateTime  time.Time `gorm:"column:create_time;index:idx_task_create_time;index:idx_task_type_create_time,priority:2;index:idx_task_status_create_time,priority:2" json:"create_time"`
 	RunEndpoint string    `gorm:"column:run_endpoint" json:"run_endpoint"`
-	Status      string    `gorm:"column:status" json:"status"`
+	Status      string    `gorm:"column:status;index:idx_task_status_create_time,priority:1" json:"status"`
 	Result      string    `gorm:"column:result" json:"result"`
 	Message     string    `gorm:"column:message" json:"message"`
+
+	// 创建任务时 TaskConfig 的修订号
+	ConfigRevision int `gorm:"column:config_revision" json:"config_revision"`
+	// 创建人；输入包含模板时 InputTemplate 保存原始模板，Input 为实际下发的渲染结果
+	Creator       string `gorm:"column:creator;index" json:"creator"`
+	InputTemplate string `gorm:"column:input_template" json:"input_template"`
+	// 创建时表单中的敏感字段路径，未配置 SECRET_KEY 时这些字段为明文，脱敏与输出遮盖都按路径处理
+	SensitiveFields [][]string `gorm:"column:sensitive_fields;serializer:json" json:"-"`
+	// 重跑或复制出的任务记录来源任务，Origin 为 rerun 或 clone
+	ParentID uint   `gorm:"column:parent_id;index" json:"parent_id"`
+	Origin   string `gorm:"column:origin" json:"origin"`
+	// 下发、开始（第一行输出或 running 回调）与结束时间，DurationMs 为结束与开始之间的毫秒数
+	DispatchedAt *time.Time `gorm:"column:dispatched_at" json:"dispatched_at"`
+	StartedAt    *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	DurationMs   *int64     `gorm:"column:duration_ms;index" json:"duration_ms"`
+	// 死信任务被确认后不再出现在死信列表中
+	AcknowledgedAt *time.Time `gorm:"column:acknowledged_at" json:"acknowledged_at"`
+	AcknowledgedBy string     `gorm:"column:acknowledged_by" json:"acknowledged_by"`
+	// 详情接口中的来源链与直接重跑出的任务，不落库
+	RerunChain []TaskLink `gorm:"-" json:"rerun_chain,omitempty"`
+	Reruns     []TaskLink `gorm:"-" json:"reruns,omitempty"`
+	// 下发给执行端的令牌，执行端凭它拉取包含密钥的输入，不在接口中返回
+	Token string `gorm:"column:token" json:"-"`
 }
 
 // 设置表名
@@ -39,10 +68,46 @@ func (TaskOutput) TableName() string {
 }
 
 type TaskConfig struct {
-	TaskType    string `gorm:"column:task_type" json:"task_type"`
+	TaskType    string `gorm:"column:task_type;primaryKey" json:"task_type"`
 	Title       string `gorm:"column:title" json:"title"`
 	Form        string `gorm:"column:form" json:"form"`
 	RunEndpoint string `gorm:"column:run_endpoint" json:"run_endpoint"`
+	Revision    int    `gorm:"column:revision" json:"revision"` // 当前修订号
+
+	// 展示信息：分类、排序（越小越靠前）、描述、负责人、标签与图标
+	Category    string   `gorm:"column:category;index" json:"category"`
+	SortOrder   int      `gorm:"column:sort_order" json:"sort_order"`
+	Description string   `gorm:"column:description" json:"description"`
+	Owner       string   `gorm:"column:owner" json:"owner"`
+	Tags        []string `gorm:"column:tags;serializer:json" json:"tags"`
+	Icon        string   `gorm:"column:icon" json:"icon"`
+
+	// 停用的任务类型拒绝创建新任务并返回维护信息；QueueWhenDisabled 为 true 时新任务排队，重新启用后再下发
+	Enabled            *bool  `gorm:"column:enabled;default:true" json:"enabled"`
+	MaintenanceMessage string `gorm:"column:maintenance_message" json:"maintenance_message"`
+	QueueWhenDisabled  bool   `gorm:"column:queue_when_disabled" json:"queue_when_disabled"`
+
+	// 由配置目录管理的配置只读，ManagedSource 为对应的文件路径
+	Managed       bool   `gorm:"column:managed" json:"managed"`
+	ManagedSource string `gorm:"column:managed_source" json:"managed_source"`
+
+	// 输出保留策略，为 0 或空时使用全局配置
+	RetentionMaxAge   int   `gorm:"column:retention_max_age" json:"retention_max_age"` // 秒
+	RetentionMaxLines int   `gorm:"column:retention_max_lines" json:"retention_max_lines"`
+	RetentionArchive  *bool `gorm:"column:retention_archive" json:"retention_archive"`
+
+	// 下发设置：超时（秒，0 使用全局配置）、请求头、认证、TLS 与代理
+	// 请求头可以使用 ${secret:NAME} 引用密钥库中的密钥；认证密码或令牌与客户端私钥只能是密钥引用，
+	// 配置本身会出现在列表、导出与修订记录中，不能保存明文
+	DispatchTimeout int               `gorm:"column:dispatch_timeout" json:"dispatch_timeout"`
+	DispatchHeaders map[string]string `gorm:"column:dispatch_headers;serializer:json" json:"dispatch_headers"`
+	AuthType        string            `gorm:"column:auth_type" json:"auth_type"` // basic 或 bearer
+	AuthUsername    string            `gorm:"column:auth_username" json:"auth_username"`
+	AuthPassword    string            `gorm:"column:auth_password" json:"auth_password"` // basic 的密码或 bearer 的令牌
+	CACert          string            `gorm:"column:ca_cert" json:"ca_cert"`             // PEM 格式的 CA 证书
+	ClientCert      string            `gorm:"column:client_cert" json:"client_cert"`     // PEM 格式的客户端证书
+	ClientKey       string            `gorm:"column:client_key" json:"client_key"`
+	ProxyURL        string            `gorm:"column:proxy_url" json:"proxy_url"`
 }
 
 // 设置表名
@@ -50,51 +115,115 @@ func (TaskConfig) TableName() string {
 	return "task_config"
 }
 
+// MarshalJSON 认证密码与客户端私钥不是密钥引用时脱敏，避免早期保存的明文出现在响应与修订中
+func (c TaskConfig) MarshalJSON() ([]byte, error) {
+	type plainTaskConfig TaskConfig
+	c.redactCredentials()
+	return json.Marshal(plainTaskConfig(c))
+}
+
+// redactCredentials 将不是密钥引用的认证密码与客户端私钥替换为脱敏值
+func (c *TaskConfig) redactCredentials() {
+	if c.AuthPassword != "" && !isSecretRef(c.AuthPassword) {
+		c.AuthPassword = RedactedValue
+	}
+	if c.ClientKey != "" && !isSecretRef(c.ClientKey) {
+		c.ClientKey = RedactedValue
+	}
+}
+
+// IsEnabled 未设置时视为启用
+func (c *TaskConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
 func InitDB() error {
 	var err error
-	db, err = gorm.Open(sqlite.Open(GetConfig().Database), &gorm.Config{})
+	db, err = gorm.Open(sqlite.Open(buildDSN(GetConfig())), &gorm.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to connect database: %w", err)
 	}
 
 	// 自动迁移创建表
 	db.AutoMigrate(&Task{})
+	if err := migrateTaskConfigKey(); err != nil {
+		return fmt.Errorf("failed to migrate task_config: %w", err)
+	}
 	db.AutoMigrate(&TaskConfig{})
 	db.AutoMigrate(&TaskOutput{})
+	db.AutoMigrate(&TaskOutputArchive{})
+	db.AutoMigrate(&TaskConfigRevision{})
+	db.AutoMigrate(&TaskPreset{})
+	db.AutoMigrate(&TaskSecret{})
+	db.AutoMigrate(&TaskStatsRollup{}, &TaskStatsRollupState{})
+
+	if err := initSearchIndex(); err != nil {
+		return fmt.Errorf("failed to init search index: %w", err)
+	}
+	if err := initOutputBuffer(); err != nil {
+		return fmt.Errorf("failed to init output buffer: %w", err)
+	}
 	return nil
 }
 
-// CreateInput 创建任务并保存到数据库
-func CreateTask(taskType string, input string, runEndpoint string) (uint, error) {
+// buildDSN 为数据库连接开启 WAL 模式和忙等待，pragma 会在连接池的每个连接上执行
+func buildDSN(cfg *AppConfig) string {
+	pragmas := []string{
+		"_pragma=journal_mode(WAL)",
+		fmt.Sprintf("_pragma=busy_timeout(%d)", cfg.DBBusyTimeout),
+		"_pragma=synchronous(NORMAL)",
+	}
+	separator := "?"
+	if strings.Contains(cfg.Database, "?") {
+		separator = "&"
+	}
+	return cfg.Database + separator + strings.Join(pragmas, "&")
+}
+
+// CreateTask 创建任务并保存到数据库，ID 由 SQLite 自动生成
+//
+// 未设置的运行地址和状态从 TaskConfig 中补齐：任务类型停用且允许排队时状态为 queued，否则为 ready。
+func CreateTask(task *Task) error {
 	// 生成当前时间
-	now := time.Now()
+	task.CreateTime = time.Now()
 
 	// 如果前端没有提供runEndpoint，尝试从TaskConfig中获取
-	if runEndpoint == "" {
-		var taskConfig TaskConfig
-		if err := db.Where("task_type = ?", taskType).First(&taskConfig).Error; err == nil {
-			if runEndpoint == "" {
-				runEndpoint = taskConfig.RunEndpoint
-			}
+	var taskConfig TaskConfig
+	if err := db.Where("task_type = ?", task.TaskType).First(&taskConfig).Error; err == nil {
+		if task.RunEndpoint == "" {
+			task.RunEndpoint = taskConfig.RunEndpoint
+		}
+		task.ConfigRevision = taskConfig.Revision
+		if err := encryptTaskSensitiveFields(task, taskConfig.Form); err != nil {
+			return err
 		}
 	}
-
-	// 创建Task实例，ID由SQLite自动生成
-	task := Task{
-		TaskType:    taskType,
-		Input:       input,
-		CreateTime:  now,
-		RunEndpoint: runEndpoint,
-		Status:      "ready",
+	if task.Status == "" {
+		task.Status = TaskStatusReady
 	}
+	task.Token = util.RandomToken(16)
 
 	// 保存到数据库
-	if result := db.Create(&task); result.Error != nil {
-		return 0, result.Error
-	}
+	return insertTask(task)
+}
 
-	// 将uint类型的ID转换为string返回
-	return task.ID, nil
+// insertTask 保存新任务，并在同一事务中为输入建立全文索引
+func insertTask(task *Task) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		return indexTaskInput(tx, task)
+	})
+}
+
+// MarshalJSON 接口响应中的输入对敏感字段脱敏，执行端通过 ResolveTaskInput 获取明文
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plainTask Task
+	redacted := plainTask(t)
+	redacted.Input = RedactTaskInput(t.Input, t.SensitiveFields)
+	redacted.InputTemplate = RedactTaskInput(t.InputTemplate, t.SensitiveFields)
+	return json.Marshal(redacted)
 }
 
 // GetTask 根据ID从数据库获取任务
@@ -107,29 +236,31 @@ func GetTask(taskID uint) *Task {
 	return &task
 }
 
-// ListTasks 查询任务列表，支持分页和任务类型过滤
-func ListTasks(taskType string, page int, pageSize int) ([]*Task, int64, error) {
+// ListTasks 按筛选条件分页查询任务列表
+func ListTasks(filter TaskFilter, page int, pageSize int) ([]*Task, int64, error) {
 	var tasks []*Task
 	var total int64
 
 	// 构建查询
-	query := db.Model(&Task{})
-
-	// 如果提供了任务类型，则添加过滤条件
-	if taskType != "" {
-		query = query.Where("task_type = ?", taskType)
+	query, err := filter.apply(db.Model(&Task{}))
+	if err != nil {
+		return nil, 0, err
+	}
+	order, err := filter.order()
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// 获取总记录数
-	if err := query.Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 
-	// 执行分页查询，按创建时间倒序排列
-	if err := query.Offset(offset).Limit(pageSize).Order("create_time DESC").Find(&tasks).Error; err != nil {
+	// 执行分页查询
+	if err := query.Offset(offset).Limit(pageSize).Order(order).Find(&tasks).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -139,37 +270,155 @@ func ListTasks(taskType string, page int, pageSize int) ([]*Task, int64, error)
 // DeleteTask 根据ID删除任务及关联的任务输出
 func DeleteTask(taskID uint) error {
 	// 先删除关联的任务输出
+	dropOutputRing(taskID)
+	forgetOutputMasks(taskID)
+	startedTasks.Delete(taskID)
 	if err := db.Where("task_id = ?", taskID).Delete(&TaskOutput{}).Error; err != nil {
 		return err
 	}
+	if err := deleteTaskOutputArchives(taskID); err != nil {
+		return err
+	}
 	// 再删除任务
 	result := db.Delete(&Task{}, taskID)
 	return result.Error
 }
 
 func CallbackTask(taskID uint, status string, result string, message string) error {
+	// 结果与消息和输出一样遮盖敏感值
 	updateData := map[string]interface{}{
 		"status":  status,
-		"result":  result,
-		"message": message,
+		"result":  MaskTaskOutput(taskID, result),
+		"message": MaskTaskOutput(taskID, message),
+	}
+	switch {
+	case status == TaskStatusRunning:
+		markTaskStarted(taskID)
+	case IsTerminalStatus(status):
+		for column, value := range finishedColumns(taskID, time.Now()) {
+			updateData[column] = value
+		}
 	}
-	return db.Table("task").Where("id = ?", taskID).Updates(updateData).Error
+	// 任务状态变化时尽快落库缓冲中的输出
+	NotifyTaskOutputFlush()
+	if err := db.Table("task").Where("id = ?", taskID).Updates(updateData).Error; err != nil {
+		return err
+	}
+	if IsTerminalStatus(status) {
+		forgetOutputMasks(taskID)
+	}
+	notifyTask(taskID)
+	return nil
 }
 
-func UpdateTaskConfig(taskType string, taskConfig *TaskConfig) error {
-	updateData := map[string]interface{}{
-		"title":        taskConfig.Title,
-		"form":         taskConfig.Form,
-		"run_endpoint": taskConfig.RunEndpoint,
+// taskConfigColumns 返回可以通过更新接口修改的配置字段
+func taskConfigColumns(taskConfig *TaskConfig) map[string]interface{} {
+	tags, _ := json.Marshal(taskConfig.Tags)
+	headers, _ := json.Marshal(taskConfig.DispatchHeaders)
+	return map[string]interface{}{
+		"title":               taskConfig.Title,
+		"category":            taskConfig.Category,
+		"sort_order":          taskConfig.SortOrder,
+		"description":         taskConfig.Description,
+		"owner":               taskConfig.Owner,
+		"tags":                string(tags),
+		"icon":                taskConfig.Icon,
+		"form":                taskConfig.Form,
+		"run_endpoint":        taskConfig.RunEndpoint,
+		"retention_max_age":   taskConfig.RetentionMaxAge,
+		"retention_max_lines": taskConfig.RetentionMaxLines,
+		"retention_archive":   taskConfig.RetentionArchive,
+		"enabled":             taskConfig.IsEnabled(),
+		"maintenance_message": taskConfig.MaintenanceMessage,
+		"queue_when_disabled": taskConfig.QueueWhenDisabled,
+		"dispatch_timeout":    taskConfig.DispatchTimeout,
+		"dispatch_headers":    string(headers),
+		"auth_type":           taskConfig.AuthType,
+		"auth_username":       taskConfig.AuthUsername,
+		"auth_password":       taskConfig.AuthPassword,
+		"ca_cert":             taskConfig.CACert,
+		"client_cert":         taskConfig.ClientCert,
+		"client_key":          taskConfig.ClientKey,
+		"proxy_url":           taskConfig.ProxyURL,
 	}
-	fmt.Printf("updateData: %v\n", updateData)
-	result := db.Table("task_config").Where("task_type = ?", taskType).Updates(updateData)
-	return result.Error
 }
 
-func CreateTaskConfig(taskConfig *TaskConfig) error {
-	result := db.Create(taskConfig)
-	return result.Error
+// UpdateTaskConfig 更新配置并记录修订，author 为操作人
+func UpdateTaskConfig(taskType string, taskConfig *TaskConfig, author string) error {
+	return saveTaskConfig(taskType, taskConfig, author, RevisionActionUpdate)
+}
+
+// saveTaskConfig 在同一事务中更新配置并记录修订，回滚时配置已被删除则重新创建
+func saveTaskConfig(taskType string, taskConfig *TaskConfig, author string, action string) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return applyTaskConfig(tx, taskType, taskConfig, author, action)
+	})
+	if err != nil {
+		return err
+	}
+	// 重新启用后下发停用期间排队的任务
+	if taskConfig.IsEnabled() {
+		go DispatchQueuedTasks(taskType)
+	}
+	return nil
+}
+
+// applyTaskConfig 在事务 tx 中更新配置并记录修订，完成后 taskConfig 为更新后的配置
+func applyTaskConfig(tx *gorm.DB, taskType string, taskConfig *TaskConfig, author string, action string) error {
+	var oldConfig *TaskConfig
+	var existing TaskConfig
+	if err := tx.Where("task_type = ?", taskType).First(&existing).Error; err == nil {
+		oldConfig = &existing
+	}
+
+	if oldConfig == nil {
+		if action != RevisionActionRollback {
+			return ErrTaskConfigNotFound
+		}
+		restored := *taskConfig
+		restored.TaskType = taskType
+		restored.Managed, restored.ManagedSource = false, ""
+		if err := tx.Create(&restored).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Table("task_config").Where("task_type = ?", taskType).Updates(taskConfigColumns(taskConfig)).Error; err != nil {
+		return err
+	}
+
+	var updated TaskConfig
+	if err := tx.Where("task_type = ?", taskType).First(&updated).Error; err != nil {
+		return err
+	}
+	if _, err := saveTaskConfigRevision(tx, oldConfig, &updated, author, action); err != nil {
+		return err
+	}
+	*taskConfig = updated
+	return nil
+}
+
+// CreateTaskConfig 创建配置并记录第一个修订，author 为操作人
+func CreateTaskConfig(taskConfig *TaskConfig, author string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return insertTaskConfig(tx, taskConfig, author)
+	})
+}
+
+// insertTaskConfig 在事务 tx 中创建配置并记录第一个修订
+func insertTaskConfig(tx *gorm.DB, taskConfig *TaskConfig, author string) error {
+	// 管理状态只由配置目录同步设置
+	taskConfig.Managed, taskConfig.ManagedSource = false, ""
+	var count int64
+	if err := tx.Model(&TaskConfig{}).Where("task_type = ?", taskConfig.TaskType).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTaskConfigExists
+	}
+	if err := tx.Create(taskConfig).Error; err != nil {
+		return err
+	}
+	_, err := saveTaskConfigRevision(tx, nil, taskConfig, author, RevisionActionCreate)
+	return err
 }
 
 func GetTaskConfig(taskType string) *TaskConfig {
@@ -181,34 +430,140 @@ func GetTaskConfig(taskType string) *TaskConfig {
 	return &taskConfig
 }
 
-func GetTaskConfigList() ([]*TaskConfig, error) {
+// TaskConfigFilter 配置列表的过滤条件
+type TaskConfigFilter struct {
+	Category string
+	Tag      string
+}
+
+// GetTaskConfigList 按分类和标签过滤配置，按 sort_order、title、task_type 稳定排序
+func GetTaskConfigList(filter TaskConfigFilter) ([]*TaskConfig, error) {
 	var taskConfigs []*TaskConfig
-	result := db.Find(&taskConfigs)
+	query := db.Model(&TaskConfig{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(task_config.tags) WHERE json_each.value = ?)", filter.Tag)
+	}
+	result := query.Order("sort_order ASC, title ASC, task_type ASC").Find(&taskConfigs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return taskConfigs, nil
 }
 
-func DeleteTaskConfig(taskType string) error {
-	result := db.Where("task_type = ?", taskType).Delete(&TaskConfig{})
-	return result.Error
+// DeleteTaskConfig 删除配置，修订历史保留，可以通过回滚恢复
+func DeleteTaskConfig(taskType string, author string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing TaskConfig
+		if err := tx.Where("task_type = ?", taskType).First(&existing).Error; err != nil {
+			return ErrTaskConfigNotFound
+		}
+		if err := tx.Where("task_type = ?", taskType).Delete(&TaskConfig{}).Error; err != nil {
+			return err
+		}
+		_, err := saveTaskConfigRevision(tx, &existing, &existing, author, RevisionActionDelete)
+		return err
+	})
 }
 
+// CreateTaskOutput 写入任务输出，输出先进入内存缓冲，由后台批量落库，任务不存在时返回 ErrTaskNotFound
 func CreateTaskOutput(taskID uint, output string) error {
-	result := db.Create(&TaskOutput{
-		TaskID:     taskID,
-		Output:     output,
-		CreateTime: time.Now(),
-	})
-	return result.Error
+	if taskID == 0 {
+		return ErrTaskNotFound
+	}
+	masks, found := taskOutputMasks(taskID)
+	if !found {
+		return ErrTaskNotFound
+	}
+	appendTaskOutput(taskID, maskOutput(masks, output))
+	markTaskStarted(taskID)
+	return nil
 }
 
+// GetTaskOutput 查询 ID 大于 lastID 的任务输出，尚未落库的输出从内存缓冲中读取
 func GetTaskOutput(taskID uint, lastID int, pageSize int) ([]TaskOutput, error) {
-	var taskOutputs []TaskOutput
-	result := db.Where("task_id = ? AND id > ?", taskID, lastID).Order("id asc").Limit(pageSize).Find(&taskOutputs)
-	if result.Error != nil {
-		return nil, result.Error
+	buffered, covered := readBufferedOutput(taskID, lastID, pageSize)
+	if covered {
+		return buffered, nil
+	}
+
+	// 已归档的输出总是早于数据库中的输出，先从归档中读取
+	taskOutputs, err := readArchivedOutput(taskID, lastID, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(taskOutputs) > 0 {
+		if pageSize > 0 && len(taskOutputs) >= pageSize {
+			return taskOutputs, nil
+		}
+		lastID = int(taskOutputs[len(taskOutputs)-1].ID)
+	}
+
+	var dbOutputs []TaskOutput
+	query := db.Where("task_id = ? AND id > ?", taskID, lastID).Order("id asc")
+	if pageSize > 0 {
+		query = query.Limit(pageSize - len(taskOutputs))
+	}
+	if err := query.Find(&dbOutputs).Error; err != nil {
+		return nil, err
+	}
+	taskOutputs = append(taskOutputs, dbOutputs...)
+
+	// 补充缓冲中还未落库的输出
+	if len(taskOutputs) < pageSize || pageSize <= 0 {
+		maxID := lastID
+		if len(taskOutputs) > 0 {
+			maxID = int(taskOutputs[len(taskOutputs)-1].ID)
+		}
+		for _, row := range buffered {
+			if int(row.ID) <= maxID {
+				continue
+			}
+			if pageSize > 0 && len(taskOutputs) >= pageSize {
+				break
+			}
+			taskOutputs = append(taskOutputs, row)
+		}
 	}
 	return taskOutputs, nil
 }
+
+// GetTaskOutputTail 获取任务最后 lines 行输出
+func GetTaskOutputTail(taskID uint, lines int) ([]TaskOutput, error) {
+	if lines <= 0 {
+		return []TaskOutput{}, nil
+	}
+	buffered, _ := readBufferedOutput(taskID, 0, 0)
+	if len(buffered) >= lines {
+		return buffered[len(buffered)-lines:], nil
+	}
+
+	query := db.Where("task_id = ?", taskID)
+	if len(buffered) > 0 {
+		query = query.Where("id < ?", buffered[0].ID)
+	}
+	var dbOutputs []TaskOutput
+	if err := query.Order("id desc").Limit(lines - len(buffered)).Find(&dbOutputs).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(dbOutputs)
+	tail := append(dbOutputs, buffered...)
+
+	// 数据库中的输出不足时从归档中补充
+	if len(tail) < lines {
+		archived, err := readArchivedOutput(taskID, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(tail) > 0 {
+			archived = slices.DeleteFunc(archived, func(output TaskOutput) bool { return output.ID >= tail[0].ID })
+		}
+		if n := lines - len(tail); len(archived) > n {
+			archived = archived[len(archived)-n:]
+		}
+		tail = append(archived, tail...)
+	}
+	return tail, nil
+}
@@ -0,0 +1,130 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 任务列表的排序字段
+const (
+	TaskSortCreateTime = "create_time"
+	TaskSortStatus     = "status"
+	TaskSortDuration   = "duration"
+)
+
+// TaskFilter 任务列表的筛选与排序条件，空字段不过滤
+//
+// 批次与工作流目前不存在，因此没有对应的筛选条件。
+type TaskFilter struct {
+	TaskType       string
+	Statuses       []string
+	StartTime      *time.Time
+	EndTime        *time.Time
+	Creator        string
+	Tags           []string // 任务类型配置中的标签，需要同时包含全部标签
+	InputContains  string   // 输入子串，使用全文索引
+	ResultContains string   // 结果子串，使用全文索引
+	InputPath      string   // 输入的 JSON 路径，如 $.user.name 或 user.name，与 InputValue 一起使用
+	InputValue     string
+	ResultPath     string
+	ResultValue    string
+	MinDuration    *time.Duration // 只包含已记录耗时的任务
+	MaxDuration    *time.Duration
+	Sort           string
+	Desc           bool
+}
+
+// jsonPathPattern 只允许由字段名与数组下标组成的路径，避免把任意表达式交给 json_extract
+var jsonPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z0-9_\-]+|\[[0-9]+\])*$`)
+
+// normalizeJSONPath 补全 $ 前缀并校验路径格式
+func normalizeJSONPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "$") {
+		path = "$." + path
+	}
+	if !jsonPathPattern.MatchString(path) {
+		return "", fmt.Errorf("无效的 JSON 路径：%s", path)
+	}
+	return path, nil
+}
+
+// applyContains 子串匹配，三个字符以上使用 trigram 全文索引，更短的子串只能逐行匹配
+func applyContains(query *gorm.DB, source string, value string) *gorm.DB {
+	if utf8.RuneCountInString(value) >= 3 {
+		phrase := `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		return query.Where("task.id IN (SELECT task_id FROM task_search WHERE task_search MATCH ? AND source = ?)", phrase, source)
+	}
+	return query.Where("task."+source+" LIKE ?", "%"+value+"%")
+}
+
+// apply 将筛选条件应用到查询上
+func (f *TaskFilter) apply(query *gorm.DB) (*gorm.DB, error) {
+	if f.TaskType != "" {
+		query = query.Where("task.task_type = ?", f.TaskType)
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where("task.status IN ?", f.Statuses)
+	}
+	if f.StartTime != nil {
+		query = query.Where("task.create_time >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		query = query.Where("task.create_time < ?", *f.EndTime)
+	}
+	if f.Creator != "" {
+		query = query.Where("task.creator = ?", f.Creator)
+	}
+	if f.MinDuration != nil {
+		query = query.Where("task.duration_ms >= ?", f.MinDuration.Milliseconds())
+	}
+	if f.MaxDuration != nil {
+		query = query.Where("task.duration_ms <= ?", f.MaxDuration.Milliseconds())
+	}
+	for _, tag := range f.Tags {
+		query = query.Where("task.task_type IN (SELECT task_type FROM task_config, json_each(task_config.tags) WHERE json_each.value = ?)", tag)
+	}
+	if f.InputContains != "" {
+		query = applyContains(query, "input", f.InputContains)
+	}
+	if f.ResultContains != "" {
+		query = applyContains(query, "result", f.ResultContains)
+	}
+	for _, match := range []struct{ column, path, value string }{
+		{"input", f.InputPath, f.InputValue},
+		{"result", f.ResultPath, f.ResultValue},
+	} {
+		if match.path == "" {
+			continue
+		}
+		path, err := normalizeJSONPath(match.path)
+		if err != nil {
+			return nil, &ValidationError{Fields: []FieldError{{Field: match.column + "_path", Message: err.Error()}}}
+		}
+		// 不是合法 JSON 的行直接跳过，否则 json_extract 会使整个查询失败
+		query = query.Where("json_valid(task."+match.column+") AND CAST(json_extract(task."+match.column+", ?) AS TEXT) = ?", path, match.value)
+	}
+	return query, nil
+}
+
+// order 返回排序子句，相同排序值按 ID 保持稳定顺序
+func (f *TaskFilter) order() (string, error) {
+	direction := "ASC"
+	if f.Desc {
+		direction = "DESC"
+	}
+	switch f.Sort {
+	case "", TaskSortCreateTime:
+		return fmt.Sprintf("task.create_time %s, task.id %s", direction, direction), nil
+	case TaskSortStatus:
+		return fmt.Sprintf("task.status %s, task.create_time DESC, task.id DESC", direction), nil
+	case TaskSortDuration:
+		// 没有耗时的任务总是排在最后
+		return fmt.Sprintf("task.duration_ms IS NULL, task.duration_ms %s, task.id DESC", direction), nil
+	}
+	return "", &ValidationError{Fields: []FieldError{{Field: "sort", Message: fmt.Sprintf("不支持的排序字段：%s", f.Sort)}}}
+}
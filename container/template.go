@@ -0,0 +1,186 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TemplateContext 渲染任务输入模板时可用的上下文
+type TemplateContext struct {
+	TaskType string
+	User     string
+}
+
+// templateFuncs 输入模板中可用的函数
+//
+//	{{ now | addDays -1 | date "2006-01-02" }}  昨天的日期
+//	{{ env "TASK_ENV_REGION" }}               环境变量，只能读取 TASK_ENV_ 前缀的变量
+//	{{ uuid }}                                随机 UUID
+//	{{ user }}                                当前操作人
+//	{{ prevResult }}                          同类型上一次成功任务的结果，配合 fromJSON 取字段
+//	{{ secret "DB_PASSWORD" }}                密钥引用，执行端拉取输入时才替换为明文
+//
+// 需要原样保留的 {{ 写作 \{{（JSON 中为 "\\{{"）。
+func templateFuncs(ctx TemplateContext) template.FuncMap {
+	return template.FuncMap{
+		"now":        time.Now,
+		"date":       func(layout string, t time.Time) string { return t.Format(layout) },
+		"unix":       func(t time.Time) int64 { return t.Unix() },
+		"addDays":    func(days int, t time.Time) time.Time { return t.AddDate(0, 0, days) },
+		"addHours":   func(hours int, t time.Time) time.Time { return t.Add(time.Duration(hours) * time.Hour) },
+		"addMinutes": func(minutes int, t time.Time) time.Time { return t.Add(time.Duration(minutes) * time.Minute) },
+		"env":        templateEnv,
+		"uuid":       func() string { return uuid.NewString() },
+		"user":       func() string { return ctx.User },
+		"taskType":   func() string { return ctx.TaskType },
+		"prevResult": func() string { return previousResult(ctx.TaskType) },
+		"secret":     SecretRef,
+		"fromJSON": func(value string) (interface{}, error) {
+			var result interface{}
+			if err := json.Unmarshal([]byte(value), &result); err != nil {
+				return nil, err
+			}
+			return result, nil
+		},
+	}
+}
+
+// templateEnvPrefix 模板可以读取的环境变量前缀，避免通过任务输入读出 SECRET_KEY、PASSWORD 等配置
+const templateEnvPrefix = "TASK_ENV_"
+
+func templateEnv(name string) (string, error) {
+	if !strings.HasPrefix(name, templateEnvPrefix) {
+		return "", fmt.Errorf("只能读取 %s 前缀的环境变量：%s", templateEnvPrefix, name)
+	}
+	return os.Getenv(name), nil
+}
+
+// escapedDelim 转义的模板起始符，渲染时原样输出为 {{
+const escapedDelim = `\{{`
+
+// escapePlaceholder 渲染期间代替转义起始符的占位符，不会出现在正常输入中
+const escapePlaceholder = "\x00LBRACE\x00"
+
+// previousResult 返回同类型最近一次成功任务的结果
+func previousResult(taskType string) string {
+	var task Task
+	err := db.Where("task_type = ? AND status = ?", taskType, TaskStatusSuccess).Order("id DESC").First(&task).Error
+	if err != nil {
+		return ""
+	}
+	return task.Result
+}
+
+// HasInputTemplate 判断输入中是否包含模板表达式
+func HasInputTemplate(input string) bool {
+	return strings.Contains(input, "{{")
+}
+
+// RenderInputTemplate 渲染输入中的模板表达式
+//
+// 输入是 JSON 对象，只渲染其中包含 {{ 的字符串值，因此模板中的引号不需要按 JSON 转义，
+// 渲染结果也不会破坏 JSON 结构。
+func RenderInputTemplate(input string, ctx TemplateContext) (string, error) {
+	if !HasInputTemplate(input) {
+		return input, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return "", &ValidationError{Fields: []FieldError{{Field: "input", Message: "输入不是有效的 JSON 对象"}}}
+	}
+
+	var errs []FieldError
+	funcs := templateFuncs(ctx)
+	var render func(path string, value interface{}) interface{}
+	render = func(path string, value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			if !HasInputTemplate(v) {
+				return v
+			}
+			text := strings.ReplaceAll(v, escapedDelim, escapePlaceholder)
+			if HasInputTemplate(text) {
+				tmpl, err := template.New(path).Funcs(funcs).Option("missingkey=error").Parse(text)
+				if err != nil {
+					errs = append(errs, FieldError{Field: path, Message: "模板解析失败：" + err.Error()})
+					return v
+				}
+				var buf bytes.Buffer
+				if err := tmpl.Execute(&buf, nil); err != nil {
+					errs = append(errs, FieldError{Field: path, Message: "模板渲染失败：" + err.Error()})
+					return v
+				}
+				text = buf.String()
+			}
+			return strings.ReplaceAll(text, escapePlaceholder, "{{")
+		case map[string]interface{}:
+			for key, item := range v {
+				v[key] = render(joinPath(path, key), item)
+			}
+			return v
+		case []interface{}:
+			for i, item := range v {
+				v[i] = render(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+			return v
+		default:
+			return v
+		}
+	}
+	value = render("", value)
+	if len(errs) > 0 {
+		return "", &ValidationError{Fields: errs}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// EscapeInputTemplate 将已渲染输入中的 {{ 转义为 \{{，以它为基础创建新任务时原样保留而不再次渲染
+func EscapeInputTemplate(input string) (string, error) {
+	if !HasInputTemplate(input) {
+		return input, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return "", &ValidationError{Fields: []FieldError{{Field: "input", Message: "输入不是有效的 JSON 对象"}}}
+	}
+	var escape func(value interface{}) interface{}
+	escape = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return strings.ReplaceAll(v, "{{", escapedDelim)
+		case map[string]interface{}:
+			for key, item := range v {
+				v[key] = escape(item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				v[i] = escape(item)
+			}
+		}
+		return value
+	}
+	data, err := json.Marshal(escape(value))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
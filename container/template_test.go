@@ -0,0 +1,57 @@
+package container
+
+import (
+	"testing"
+)
+
+func TestRenderInputTemplate(t *testing.T) {
+	t.Setenv("TASK_ENV_REGION", "cn-north")
+	t.Setenv("SECRET_KEY", "master")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", `{"a":"b"}`, `{"a":"b"}`, false},
+		{"user", `{"who":"{{ user }}"}`, `{"who":"bob"}`, false},
+		{"env with prefix", `{"region":"{{ env \"TASK_ENV_REGION\" }}"}`, `{"region":"cn-north"}`, false},
+		{"env without prefix", `{"key":"{{ env \"SECRET_KEY\" }}"}`, "", true},
+		{"escaped", `{"text":"\\{{ literal }}"}`, `{"text":"{{ literal }}"}`, false},
+		{"escaped and rendered", `{"text":"\\{{ {{ user }} }}"}`, `{"text":"{{ bob }}"}`, false},
+		{"invalid template", `{"text":"{{ nope"}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderInputTemplate(tt.input, TemplateContext{TaskType: "a", User: "bob"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeInputTemplateRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"a":"b"}`,
+		`{"text":"{{ literal }}"}`,
+		`{"n":1,"nested":{"list":["{{ x }}","y"]}}`,
+	}
+	for _, input := range tests {
+		escaped, err := EscapeInputTemplate(input)
+		if err != nil {
+			t.Fatalf("escape %s: %v", input, err)
+		}
+		got, err := RenderInputTemplate(escaped, TemplateContext{TaskType: "a", User: "bob"})
+		if err != nil {
+			t.Fatalf("render %s: %v", escaped, err)
+		}
+		if got != input {
+			t.Errorf("round trip of %s = %s", input, got)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package container
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// startedTasks 已记录开始时间的任务及最近一次输出的时间，避免每行输出都写一次数据库
+var startedTasks sync.Map
+
+// MarkTaskDispatched 记录任务下发给执行端的时间
+func MarkTaskDispatched(taskID uint) error {
+	return db.Model(&Task{}).Where("id = ?", taskID).Update("dispatched_at", time.Now()).Error
+}
+
+// markTaskStarted 在第一行输出或 running 回调时记录任务开始时间，已记录时忽略
+func markTaskStarted(taskID uint) {
+	if _, loaded := startedTasks.Swap(taskID, time.Now()); loaded {
+		return
+	}
+	err := db.Model(&Task{}).Where("id = ? AND started_at IS NULL", taskID).Update("started_at", time.Now()).Error
+	if err != nil {
+		startedTasks.Delete(taskID)
+		log.Printf("record start time of task %d failed: %v", taskID, err)
+	}
+}
+
+// evictIdleStartedTasks 清除 idleBefore 之后没有输出的任务标记，没有回调结束的任务不会一直占用内存
+//
+// 标记被清除后再次输出只会多执行一次不会修改数据的更新
+func evictIdleStartedTasks(idleBefore time.Time) {
+	startedTasks.Range(func(key, value interface{}) bool {
+		if value.(time.Time).Before(idleBefore) {
+			startedTasks.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
+
+// finishedColumns 任务结束时需要更新的时间字段
+//
+// 耗时从开始时间算起，执行端没有输出也没有 running 回调时从下发时间算起，两者都没有时不记录耗时。
+func finishedColumns(taskID uint, finishedAt time.Time) map[string]interface{} {
+	startedTasks.Delete(taskID)
+	columns := map[string]interface{}{"finished_at": finishedAt}
+	var task Task
+	if err := db.Select("id", "started_at", "dispatched_at").Where("id = ?", taskID).First(&task).Error; err != nil {
+		return columns
+	}
+	start := task.StartedAt
+	if start == nil {
+		start = task.DispatchedAt
+	}
+	if start != nil {
+		columns["duration_ms"] = finishedAt.Sub(*start).Milliseconds()
+	}
+	return columns
+}
@@ -9,6 +9,24 @@ export const Route = createFileRoute('/')({
     component: Index,
 })
 
+// 详情接口中敏感字段的脱敏值
+const REDACTED_VALUE = '******'
+
+// dropRedactedValues 删除对象中的脱敏值，返回是否删除过
+function dropRedactedValues(obj) {
+    let dropped = false
+    Object.keys(obj).forEach(key => {
+        const value = obj[key]
+        if (value === REDACTED_VALUE) {
+            delete obj[key]
+            dropped = true
+        } else if (value && typeof value === 'object' && !Array.isArray(value)) {
+            dropped = dropRedactedValues(value) || dropped
+        }
+    })
+    return dropped
+}
+
 function Index() {
     const [currentTaskId, setCurrentTaskId] = useState(null)
     const form = useForm()
@@ -76,9 +94,14 @@ function Index() {
                             const parsedInput = JSON.parse(task.input)
                             console.log(parsedInput, task.input, form)
 
+                            // 敏感字段在详情中已脱敏，不回填，需要重新填写
+                            const redacted = dropRedactedValues(parsedInput)
                             Object.keys(parsedInput).forEach(key => {
                                 form.setFieldValue(key, parsedInput[key])
                             })
+                            if (redacted) {
+                                message.warning('敏感字段未回填，请重新填写')
+                            }
                         } catch (error) {
                             console.error('解析任务输入参数失败:', error)
                             message.error('解析任务参数失败')
@@ -4,10 +4,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"run-task/api/file"
 	"run-task/api/task"
 	"run-task/container"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 
@@ -19,7 +21,7 @@ func CORS() gin.HandlerFunc {
 		ctx.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		ctx.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		ctx.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Disposition")
 		ctx.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if ctx.Request.Method == http.MethodOptions {
@@ -39,7 +41,25 @@ func Main() {
 	if err := container.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	container.RegisterTaskDispatcher(task.DispatchTask)
+	if err := container.StartConfigDirSync(); err != nil {
+		log.Fatalf("Failed to sync config dir: %v", err)
+	}
 	cfg := container.GetConfig()
+	container.StartOutputCompaction()
+	container.StartStatsRollup()
+	go container.DispatchQueuedTasks("")
+
+	// 退出前将缓冲中的任务输出落库
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		if err := container.FlushTaskOutput(); err != nil {
+			log.Printf("Failed to flush task output: %v", err)
+		}
+		os.Exit(0)
+	}()
 
 	// 创建 Gin 实例
 	router := gin.Default()
@@ -50,18 +70,45 @@ func Main() {
 		apiGroup.POST("/task/config/create", task.CreateTaskConfig)
 		apiGroup.POST("/task/config/update", task.UpdateTaskConfig)
 		apiGroup.POST("/task/config/delete", task.DeleteTaskConfig)
+		apiGroup.GET("/task/config/revisions", task.GetTaskConfigRevisions)
+		apiGroup.GET("/task/config/revision/compare", task.CompareTaskConfigRevisions)
+		apiGroup.POST("/task/config/rollback", task.RollbackTaskConfig)
+		apiGroup.GET("/task/config/export", task.ExportTaskConfig)
+		apiGroup.POST("/task/config/import", task.ImportTaskConfig)
+		apiGroup.GET("/task/config/drift", task.GetTaskConfigDrift)
+		apiGroup.POST("/task/config/test", task.TestTaskConfig)
+
+		apiGroup.GET("/task/preset/list", task.GetTaskPresets)
+		apiGroup.GET("/task/preset/detail", task.GetTaskPreset)
+		apiGroup.POST("/task/preset/create", task.CreateTaskPreset)
+		apiGroup.POST("/task/preset/update", task.UpdateTaskPreset)
+		apiGroup.POST("/task/preset/delete", task.DeleteTaskPreset)
+
+		apiGroup.GET("/task/secret/list", task.GetTaskSecrets)
+		apiGroup.POST("/task/secret/save", task.SaveTaskSecret)
+		apiGroup.POST("/task/secret/delete", task.DeleteTaskSecret)
 
 		apiGroup.POST("/task/create", task.CreateTask)
+		apiGroup.POST("/task/run", task.RunTask)
+		apiGroup.POST("/task/rerun/:task_id", task.RerunTask)
+		apiGroup.POST("/task/clone/:task_id", task.CloneTask)
+		apiGroup.GET("/task/dead-letter", task.GetDeadLetters)
+		apiGroup.POST("/task/dead-letter/action", task.ApplyDeadLetterAction)
 		apiGroup.GET("/task/list", task.GetTasks)
 		apiGroup.POST("/task/delete", task.DeleteTask)
 		apiGroup.GET("/task/detail", task.GetTaskByID)
+		apiGroup.GET("/task/wait/:task_id", task.WaitTask)
 
 		apiGroup.GET("/task/input/:task_id", task.GetTaskInput)
 		apiGroup.POST("/task/output/:task_id", task.PostTaskOutput)
 		apiGroup.GET("/task/output/:task_id", task.GetTaskOutput)
+		apiGroup.GET("/task/log/:task_id", task.DownloadTaskLog)
 
 		apiGroup.POST("/task/callback/:task_id", task.Callback)
 
+		apiGroup.GET("/search", task.SearchTasks)
+		apiGroup.GET("/stats", task.GetTaskStats)
+
 		apiGroup.POST("/file/upload", file.UploadFile)
 		apiGroup.GET("/file/get/*path", file.DownloadFile)
 		apiGroup.GET("/file/list", file.ListFiles)